@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/logger"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const maxCommentLen = 2000
+
+// ActivityRecorder appends to a group's feed after a mutation succeeded.
+// The feed is informational, so a failed write is logged and the request
+// still succeeds.
+type ActivityRecorder struct {
+	store db.ActivityStore
+}
+
+func NewActivityRecorder(store db.ActivityStore) *ActivityRecorder {
+	return &ActivityRecorder{store: store}
+}
+
+func (r *ActivityRecorder) record(c *fiber.Ctx, groupID, action, targetType, targetID string, data any) {
+	r.recordAs(c, actorID(c), groupID, action, targetType, targetID, data)
+}
+
+// recordAs is record for when the body already says who acted (e.g. created_by).
+func (r *ActivityRecorder) recordAs(c *fiber.Ctx, actor, groupID, action, targetType, targetID string, data any) {
+	if r == nil || groupID == "" {
+		return
+	}
+	a := &types.Activity{
+		GroupID:    groupID,
+		ActorID:    actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if data != nil {
+		if b, err := json.Marshal(data); err == nil {
+			a.Data = b
+		}
+	}
+	if err := r.store.Record(c.UserContext(), a); err != nil {
+		logger.From(c.UserContext()).Warn("activity record failed", zap.String("group_id", groupID), zap.String("action", action), zap.Error(err))
+	}
+}
+
+// actorID is who is making the request. Until auth lands clients send it
+// in X-User-ID; anything that isn't a UUID is ignored.
+func actorID(c *fiber.Ctx) string {
+	id := strings.TrimSpace(c.Get("X-User-ID"))
+	if _, err := uuid.Parse(id); err != nil {
+		return ""
+	}
+	return id
+}
+
+type ActivityHandlers struct {
+	activity    db.ActivityStore
+	comments    db.CommentStore
+	expenses    db.ExpenseStore
+	settlements db.SettlementStore
+	feed        *ActivityRecorder
+}
+
+func NewActivityHandlers(activity db.ActivityStore, comments db.CommentStore, expenses db.ExpenseStore, settlements db.SettlementStore, feed *ActivityRecorder) *ActivityHandlers {
+	return &ActivityHandlers{
+		activity:    activity,
+		comments:    comments,
+		expenses:    expenses,
+		settlements: settlements,
+		feed:        feed,
+	}
+}
+
+// GET /groups/:id/activity?since=&limit=&cursor=
+// since (RFC3339) is typically when the member last opened the group.
+func (h *ActivityHandlers) HandleGroupFeed(c *fiber.Ctx) error {
+	var since *time.Time
+	if v := c.Query("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return db.Invalid("since", "must be RFC3339")
+		}
+		since = &t
+	}
+	limit, _ := parseLimitOffset(c.Query("limit"), "")
+
+	out, next, err := h.activity.ListByGroup(c.UserContext(), c.Params("id"), since, limit, c.Query("cursor"))
+	if errors.Is(err, db.ErrBadCursor) {
+		return db.Invalid("cursor", "invalid cursor")
+	}
+	if err != nil {
+		return err
+	}
+	if next != "" {
+		c.Set("X-Next-Cursor", next)
+	}
+	if out == nil {
+		out = []*types.Activity{}
+	}
+	return c.JSON(out)
+}
+
+// ---------- COMMENTS ----------
+
+type createCommentReq struct {
+	AuthorID string `json:"author_id"`
+	Body     string `json:"body"`
+}
+
+// POST /groups/:id/expenses/:eid/comments
+func (h *ActivityHandlers) HandleCreateExpenseComment(c *fiber.Ctx) error {
+	return h.createComment(c, "expense", c.Params("eid"))
+}
+
+// POST /groups/:id/settlements/:sid/comments
+func (h *ActivityHandlers) HandleCreateSettlementComment(c *fiber.Ctx) error {
+	return h.createComment(c, "settlement", c.Params("sid"))
+}
+
+// GET /groups/:id/expenses/:eid/comments
+func (h *ActivityHandlers) HandleListExpenseComments(c *fiber.Ctx) error {
+	return h.listComments(c, "expense", c.Params("eid"))
+}
+
+// GET /groups/:id/settlements/:sid/comments
+func (h *ActivityHandlers) HandleListSettlementComments(c *fiber.Ctx) error {
+	return h.listComments(c, "settlement", c.Params("sid"))
+}
+
+func (h *ActivityHandlers) createComment(c *fiber.Ctx, targetType, targetID string) error {
+	groupID := c.Params("id")
+	var req createCommentReq
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.AuthorID == "" {
+		req.AuthorID = actorID(c)
+	}
+	var invalid db.ValidationError
+	if req.AuthorID == "" {
+		invalid.Add("author_id", "required")
+	}
+	if req.Body == "" {
+		invalid.Add("body", "required")
+	} else if len(req.Body) > maxCommentLen {
+		invalid.Add("body", fmt.Sprintf("must be at most %d bytes", maxCommentLen))
+	}
+	if err := invalid.Err(); err != nil {
+		return err
+	}
+	if status, msg := h.checkTarget(c, groupID, targetType, targetID); status != 0 {
+		return fiber.NewError(status, msg)
+	}
+
+	cm := &types.Comment{GroupID: groupID, TargetType: targetType, TargetID: targetID, AuthorID: req.AuthorID, Body: req.Body}
+	if err := h.comments.Create(c.UserContext(), cm); err != nil {
+		return err
+	}
+	h.feed.recordAs(c, cm.AuthorID, groupID, types.ActionCommentCreated, targetType, targetID, fiber.Map{"comment_id": cm.ID})
+	return c.Status(http.StatusCreated).JSON(cm)
+}
+
+func (h *ActivityHandlers) listComments(c *fiber.Ctx, targetType, targetID string) error {
+	groupID := c.Params("id")
+	if status, msg := h.checkTarget(c, groupID, targetType, targetID); status != 0 {
+		return fiber.NewError(status, msg)
+	}
+	out, err := h.comments.ListByTarget(c.UserContext(), groupID, targetType, targetID)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		out = []*types.Comment{}
+	}
+	return c.JSON(out)
+}
+
+// checkTarget makes sure the expense/settlement exists in the group;
+// a non-zero status is the error to respond with.
+func (h *ActivityHandlers) checkTarget(c *fiber.Ctx, groupID, targetType, targetID string) (int, string) {
+	var err error
+	switch targetType {
+	case "expense":
+		_, err = h.expenses.Get(c.UserContext(), groupID, targetID)
+	case "settlement":
+		_, err = h.settlements.Get(c.UserContext(), groupID, targetID)
+	}
+	if errors.Is(err, db.ErrNotFound) {
+		return http.StatusNotFound, targetType + " not found"
+	}
+	if err != nil {
+		return http.StatusInternalServerError, "failed to load " + targetType
+	}
+	return 0, ""
+}
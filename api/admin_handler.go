@@ -0,0 +1,111 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminHandlers struct {
+	audit      db.AuditStore
+	ledger     db.LedgerStore
+	adminToken string
+}
+
+// NewAdminHandlers serves the audit log and ledger checks to callers
+// presenting adminToken in X-Admin-Token. An empty token disables them.
+func NewAdminHandlers(audit db.AuditStore, ledger db.LedgerStore, adminToken string) *AdminHandlers {
+	return &AdminHandlers{audit: audit, ledger: ledger, adminToken: adminToken}
+}
+
+// RequireAdmin guards the /admin routes until we have real roles.
+func (h *AdminHandlers) RequireAdmin(c *fiber.Ctx) error {
+	if h.adminToken == "" {
+		return fiber.NewError(http.StatusNotFound, "not found")
+	}
+	got := c.Get("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(got), []byte(h.adminToken)) != 1 {
+		return fiber.NewError(http.StatusUnauthorized, "admin token required")
+	}
+	return c.Next()
+}
+
+// GET /admin/audit?group_id=&entity=&entity_id=&actor_id=&request_id=&limit=&cursor=
+func (h *AdminHandlers) HandleListAudit(c *fiber.Ctx) error {
+	var f db.AuditFilter
+	for _, p := range []struct {
+		name string
+		dst  **string
+	}{
+		{"group_id", &f.GroupID},
+		{"entity", &f.Entity},
+		{"entity_id", &f.EntityID},
+		{"actor_id", &f.ActorID},
+		{"request_id", &f.RequestID},
+	} {
+		if v := strings.TrimSpace(c.Query(p.name)); v != "" {
+			*p.dst = &v
+		}
+	}
+	limit, _ := parseLimitOffset(c.Query("limit"), "")
+
+	out, next, err := h.audit.List(c.UserContext(), f, limit, c.Query("cursor"))
+	if errors.Is(err, db.ErrBadCursor) {
+		return db.Invalid("cursor", "invalid cursor")
+	}
+	if err != nil {
+		return err
+	}
+	if next != "" {
+		c.Set("X-Next-Cursor", next)
+	}
+	if out == nil {
+		out = []*types.AuditEntry{}
+	}
+	return c.JSON(out)
+}
+
+// GET /admin/ledger/verify?group_id=
+// 200 with an empty list when the ledger is consistent, 409 with the issues otherwise.
+func (h *AdminHandlers) HandleVerifyLedger(c *fiber.Ctx) error {
+	issues, err := h.ledger.Verify(c.UserContext(), c.Query("group_id"))
+	if err != nil {
+		return err
+	}
+	status := http.StatusOK
+	if len(issues) > 0 {
+		status = http.StatusConflict
+	}
+	return c.Status(status).JSON(fiber.Map{"ok": len(issues) == 0, "issues": issues})
+}
+
+// GET /admin/ledger/journals?group_id=&source_type=expense|settlement&source_id=
+func (h *AdminHandlers) HandleListJournals(c *fiber.Ctx) error {
+	groupID, sourceType, sourceID := c.Query("group_id"), c.Query("source_type"), c.Query("source_id")
+	var invalid db.ValidationError
+	if groupID == "" {
+		invalid.Add("group_id", "required")
+	}
+	if sourceType != "expense" && sourceType != "settlement" {
+		invalid.Add("source_type", "must be expense or settlement")
+	}
+	if sourceID == "" {
+		invalid.Add("source_id", "required")
+	}
+	if err := invalid.Err(); err != nil {
+		return err
+	}
+	out, err := h.ledger.Journals(c.UserContext(), groupID, sourceType, sourceID)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		out = []*types.Journal{}
+	}
+	return c.JSON(out)
+}
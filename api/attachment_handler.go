@@ -0,0 +1,226 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/akarshgo/paysplit/blob"
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/logger"
+	"github.com/akarshgo/paysplit/thumbnail"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const MaxAttachmentBytes = 10 << 20 // 10 MB, a phone photo of a bill is ~3-5 MB
+
+// maxUploadBody is the upload route's body limit: the file plus multipart overhead.
+const maxUploadBody = MaxAttachmentBytes + 1<<20
+
+// sniffed content type => whether we try to thumbnail it
+var allowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": false,
+}
+
+type AttachmentHandlers struct {
+	expenses    db.ExpenseStore
+	attachments db.AttachmentStore
+	blobs       blob.Store
+	feed        *ActivityRecorder
+}
+
+func NewAttachmentHandlers(expenses db.ExpenseStore, attachments db.AttachmentStore, blobs blob.Store, feed *ActivityRecorder) *AttachmentHandlers {
+	return &AttachmentHandlers{expenses: expenses, attachments: attachments, blobs: blobs, feed: feed}
+}
+
+// POST /groups/:id/expenses/:eid/attachments (multipart: file, uploaded_by)
+func (h *AttachmentHandlers) HandleUploadAttachment(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	expenseID := c.Params("eid")
+	if _, err := h.expenses.Get(ctx, c.Params("id"), expenseID); err != nil {
+		return err
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return db.Invalid("file", "required")
+	}
+	if fh.Size <= 0 || fh.Size > MaxAttachmentBytes {
+		return fiber.NewError(http.StatusRequestEntityTooLarge, "file must be between 1 byte and 10 MB")
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "could not read file")
+	}
+	defer f.Close()
+
+	// trust the bytes, not the client's Content-Type
+	contentType, err := sniff(f)
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "could not read file")
+	}
+	thumbable, ok := allowedAttachmentTypes[contentType]
+	if !ok {
+		return fiber.NewError(http.StatusUnsupportedMediaType, "only JPEG, PNG, GIF, WebP images and PDFs are allowed")
+	}
+
+	id := uuid.New().String()
+	a := &types.Attachment{
+		ID:          id,
+		ExpenseID:   expenseID,
+		FileName:    cleanFileName(fh.Filename),
+		ContentType: contentType,
+		SizeBytes:   fh.Size,
+		StorageKey:  fmt.Sprintf("expenses/%s/%s", expenseID, id),
+		UploadedBy:  strings.TrimSpace(c.FormValue("uploaded_by")),
+	}
+
+	if err := h.blobs.Put(ctx, a.StorageKey, f, fh.Size, contentType); err != nil {
+		return err
+	}
+
+	if thumbable {
+		if _, err := f.Seek(0, io.SeekStart); err == nil {
+			// a receipt we can't thumbnail is still a valid upload
+			if thumb, err := thumbnail.Make(f); err == nil {
+				key := a.StorageKey + "_thumb.jpg"
+				if err := h.blobs.Put(ctx, key, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg"); err == nil {
+					a.ThumbnailKey = key
+				}
+			}
+		}
+	}
+
+	if err := h.attachments.Create(ctx, a); err != nil {
+		h.removeBlobs(ctx, a)
+		return err
+	}
+	h.feed.record(c, c.Params("id"), types.ActionAttachmentAdded, "expense", expenseID, fiber.Map{"attachment_id": id, "file_name": a.FileName})
+	return c.Status(http.StatusCreated).JSON(a)
+}
+
+// GET /groups/:id/expenses/:eid/attachments
+func (h *AttachmentHandlers) HandleListAttachments(c *fiber.Ctx) error {
+	if _, err := h.expenses.Get(c.UserContext(), c.Params("id"), c.Params("eid")); err != nil {
+		return err
+	}
+	out, err := h.attachments.ListByExpense(c.UserContext(), c.Params("eid"))
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		out = []*types.Attachment{}
+	}
+	return c.JSON(out)
+}
+
+// GET /groups/:id/expenses/:eid/attachments/:aid?thumbnail=1
+func (h *AttachmentHandlers) HandleDownloadAttachment(c *fiber.Ctx) error {
+	a, err := h.attachment(c)
+	if err != nil {
+		return err
+	}
+
+	key, contentType, name := a.StorageKey, a.ContentType, a.FileName
+	if c.QueryBool("thumbnail") {
+		if a.ThumbnailKey == "" {
+			return fiber.NewError(http.StatusNotFound, "no thumbnail for this attachment")
+		}
+		key, contentType, name = a.ThumbnailKey, "image/jpeg", "thumb_"+strings.TrimSuffix(a.FileName, filepath.Ext(a.FileName))+".jpg"
+	}
+
+	rc, err := h.blobs.Get(c.UserContext(), key)
+	if errors.Is(err, blob.ErrNotFound) {
+		return fiber.NewError(http.StatusNotFound, "file missing from storage")
+	}
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", name))
+	c.Set("X-Content-Type-Options", "nosniff")
+	return c.SendStream(rc) // fasthttp closes rc once the body is written
+}
+
+// DELETE /groups/:id/expenses/:eid/attachments/:aid
+func (h *AttachmentHandlers) HandleDeleteAttachment(c *fiber.Ctx) error {
+	a, err := h.attachment(c)
+	if err != nil {
+		return err
+	}
+	if err := h.attachments.Delete(c.UserContext(), a.ExpenseID, a.ID); err != nil && !errors.Is(err, db.ErrNotFound) {
+		return err
+	}
+	h.removeBlobs(c.UserContext(), a)
+	h.feed.record(c, c.Params("id"), types.ActionAttachmentDeleted, "expense", a.ExpenseID, fiber.Map{"attachment_id": a.ID, "file_name": a.FileName})
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// attachment loads the :aid attachment, checking it belongs to :eid in group :id.
+func (h *AttachmentHandlers) attachment(c *fiber.Ctx) (*types.Attachment, error) {
+	if _, err := h.expenses.Get(c.UserContext(), c.Params("id"), c.Params("eid")); err != nil {
+		return nil, err
+	}
+	return h.attachments.Get(c.UserContext(), c.Params("eid"), c.Params("aid"))
+}
+
+// removeBlobs is best effort: the DB row is the source of truth, a leftover
+// blob only costs storage.
+func (h *AttachmentHandlers) removeBlobs(ctx context.Context, a *types.Attachment) {
+	removeAttachmentBlobs(ctx, h.blobs, []*types.Attachment{a})
+}
+
+func removeAttachmentBlobs(ctx context.Context, blobs blob.Store, list []*types.Attachment) {
+	for _, a := range list {
+		for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+			if key == "" {
+				continue
+			}
+			if err := blobs.Delete(ctx, key); err != nil {
+				logger.From(ctx).Warn("attachment blob cleanup failed", zap.String("key", key), zap.Error(err))
+			}
+		}
+	}
+}
+
+func sniff(f multipart.File) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	ct, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	return ct, nil
+}
+
+// cleanFileName keeps just the base name so it is safe in Content-Disposition
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
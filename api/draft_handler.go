@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/smsparse"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// DraftHandlers turn forwarded bank SMS / notification text into a
+// pre-filled expense or settlement. Nothing is persisted; the client shows
+// the draft and posts it to the normal create endpoint once confirmed.
+type DraftHandlers struct {
+	users  db.UserStore
+	groups db.GroupStore
+}
+
+func NewDraftHandlers(users db.UserStore, groups db.GroupStore) *DraftHandlers {
+	return &DraftHandlers{users: users, groups: groups}
+}
+
+type smsDraftReq struct {
+	UserID     string     `json:"user_id"` // who forwarded the SMS (account holder)
+	Text       string     `json:"text"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+}
+
+type draftExpense struct {
+	createExpenseReq
+	Merchant string    `json:"merchant,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// POST /groups/:id/drafts/sms
+func (h *DraftHandlers) HandleDraftFromSMS(c *fiber.Ctx) error {
+	groupID := c.Params("id")
+	var req smsDraftReq
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
+	}
+	var invalid db.ValidationError
+	if req.UserID == "" {
+		invalid.Add("user_id", "required")
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		invalid.Add("text", "required")
+	}
+	if err := invalid.Err(); err != nil {
+		return err
+	}
+	received := time.Now()
+	if req.ReceivedAt != nil {
+		received = *req.ReceivedAt
+	}
+
+	p, err := smsparse.Parse(req.Text, received)
+	if err != nil {
+		return db.Invalid("text", "could not understand message")
+	}
+
+	members, err := h.groups.ListMembers(c.UserContext(), groupID)
+	if err != nil {
+		return err
+	}
+	isMember := map[string]bool{}
+	for _, m := range members {
+		isMember[m] = true
+	}
+	if !isMember[req.UserID] {
+		return fiber.NewError(http.StatusForbidden, "user is not a member of this group")
+	}
+
+	// money sent to / received from another member is a settlement
+	if p.VPA != "" {
+		other, err := h.users.GetByUPI(c.UserContext(), p.VPA)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+		if other != nil && other.ID != req.UserID && isMember[other.ID] {
+			from, to := req.UserID, other.ID
+			if p.Direction == smsparse.Credit {
+				from, to = other.ID, req.UserID
+			}
+			return c.JSON(fiber.Map{
+				"kind":   "settlement",
+				"parsed": p,
+				"settlement": settlementReq{
+					GroupID:     groupID,
+					FromUser:    from,
+					ToUser:      to,
+					AmountPaise: p.AmountPaise,
+					Method:      "upi",
+					Ref:         p.Ref,
+				},
+			})
+		}
+	}
+
+	if p.Direction != smsparse.Debit {
+		return &Problem{Status: http.StatusUnprocessableEntity, Code: "nothing_to_draft",
+			Detail: "credit from outside the group; nothing to draft", Extra: fiber.Map{"parsed": p}}
+	}
+
+	users := make([]types.SplitInputUser, 0, len(members))
+	for _, m := range members {
+		users = append(users, types.SplitInputUser{UserID: m})
+	}
+	return c.JSON(fiber.Map{
+		"kind":   "expense",
+		"parsed": p,
+		"expense": draftExpense{
+			createExpenseReq: createExpenseReq{
+				PaidBy:      req.UserID,
+				Note:        p.Merchant,
+				Amount:      p.AmountPaise,
+				MerchantVPA: p.VPA,
+				Split:       types.SplitInput{Kind: types.SplitEqual, Users: users},
+			},
+			Merchant: p.Merchant,
+			At:       p.At,
+		},
+	})
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/types"
+)
+
+// Two members logging the same dinner usually do it within hours of each
+// other, for the same total, with mostly the same people.
+const (
+	duplicateWindow      = 24 * time.Hour
+	duplicateCloseWindow = 2 * time.Hour
+	minParticipantMatch  = 0.5 // Jaccard over payer + split users
+	minNoteMatch         = 0.3 // Jaccard over note words
+)
+
+type duplicateCandidate struct {
+	ID        string      `json:"id"`
+	PaidBy    string      `json:"paid_by"`
+	Note      string      `json:"note"`
+	Amount    types.Money `json:"amount_paise"`
+	CreatedAt time.Time   `json:"created_at"`
+	Score     float64     `json:"score"` // 0..1, higher is more likely the same expense
+}
+
+// findDuplicates returns existing expenses in the group that look like e,
+// most likely first.
+func (h *ExpenseHandlers) findDuplicates(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit, now time.Time) ([]duplicateCandidate, error) {
+	from, to := now.Add(-duplicateWindow), now.Add(time.Minute)
+	amount := e.AmountPaise
+	recent, _, err := h.expenses.ListDetailsByGroup(ctx, e.GroupID, db.ExpenseFilter{
+		From:      &from,
+		To:        &to,
+		MinAmount: &amount,
+		MaxAmount: &amount,
+	}, 20, "")
+	if err != nil {
+		return nil, err
+	}
+
+	users := participants(e.PaidBy, splits)
+	words := noteWords(e.Note)
+	var out []duplicateCandidate
+	for _, d := range recent {
+		score, ok := duplicateScore(users, words, d, now)
+		if !ok {
+			continue
+		}
+		out = append(out, duplicateCandidate{
+			ID:        d.ID,
+			PaidBy:    d.PaidBy,
+			Note:      d.Note,
+			Amount:    d.AmountPaise,
+			CreatedAt: d.CreatedAt,
+			Score:     score,
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
+
+// duplicateScore says whether d, an expense for the same amount, looks like
+// the new one with these participants and note words, and how much. Hours
+// apart it takes matching notes too: the same people spending the same
+// amount twice in a day is ordinary (the daily auto, two lunches).
+func duplicateScore(users, words map[string]bool, d *types.ExpenseDetail, now time.Time) (float64, bool) {
+	existing := make([]types.ExpenseSplit, len(d.Splits))
+	for i, sp := range d.Splits {
+		existing[i] = sp.ExpenseSplit
+	}
+	people := jaccard(users, participants(d.PaidBy, existing))
+	if people < minParticipantMatch {
+		return 0, false
+	}
+	note := 0.0
+	if theirs := noteWords(d.Note); len(words) > 0 && len(theirs) > 0 {
+		note = jaccard(words, theirs)
+	}
+	near := now.Sub(d.CreatedAt) <= duplicateCloseWindow
+	if !near && note < minNoteMatch {
+		return 0, false
+	}
+
+	score := 0.5*people + 0.3*note
+	if near {
+		score += 0.2
+	}
+	return float64(int(score*100)) / 100, true
+}
+
+func participants(paidBy string, splits []types.ExpenseSplit) map[string]bool {
+	set := map[string]bool{paidBy: true}
+	for _, sp := range splits {
+		set[sp.UserID] = true
+	}
+	return set
+}
+
+func noteWords(note string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(note), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		set[w] = true
+	}
+	return set
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
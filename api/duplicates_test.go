@@ -0,0 +1,135 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+func detail(paidBy, note string, at time.Time, users ...string) *types.ExpenseDetail {
+	d := &types.ExpenseDetail{Expense: types.Expense{PaidBy: paidBy, Note: note, CreatedAt: at}}
+	for _, u := range users {
+		d.Splits = append(d.Splits, types.ExpenseSplitDetail{ExpenseSplit: types.ExpenseSplit{UserID: u}})
+	}
+	return d
+}
+
+func TestDuplicateScore(t *testing.T) {
+	now := time.Date(2025, 3, 1, 21, 0, 0, 0, types.IST)
+	users := map[string]bool{"a": true, "b": true, "c": true}
+	dinner := noteWords("Dinner at Toit")
+
+	tests := []struct {
+		name   string
+		words  map[string]bool
+		d      *types.ExpenseDetail
+		want   bool
+		wantGE float64
+	}{
+		{"same people and note, minutes apart", dinner, detail("a", "toit dinner", now.Add(-10*time.Minute), "a", "b", "c"), true, 0.85},
+		{"same people, no notes, within 2h", nil, detail("a", "", now.Add(-time.Hour), "a", "b", "c"), true, 0.7},
+		{"same people, no notes, 5h apart", nil, detail("a", "", now.Add(-5*time.Hour), "a", "b", "c"), false, 0},
+		{"one side noted, 5h apart", dinner, detail("a", "", now.Add(-5*time.Hour), "a", "b", "c"), false, 0},
+		{"matching notes, 5h apart", dinner, detail("a", "dinner at toit brewpub", now.Add(-5*time.Hour), "a", "b", "c"), true, 0.6},
+		{"different notes, 5h apart", dinner, detail("a", "groceries", now.Add(-5*time.Hour), "a", "b", "c"), false, 0},
+		{"different notes, minutes apart", dinner, detail("a", "groceries", now.Add(-5*time.Minute), "a", "b", "c"), true, 0.7},
+		// {a,b,c} vs {a,d}: 1 shared of 4
+		{"mostly other people", nil, detail("a", "", now.Add(-time.Minute), "a", "d"), false, 0},
+		// {a,b,c} vs {a,b}: 2 of 3, above the 0.5 cut
+		{"one person fewer", nil, detail("a", "", now.Add(-time.Minute), "a", "b"), true, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, ok := duplicateScore(users, tt.words, tt.d, now)
+			if ok != tt.want {
+				t.Fatalf("duplicate = %v (score %.2f), want %v", ok, score, tt.want)
+			}
+			if ok && (score < tt.wantGE || score > 1) {
+				t.Errorf("score = %.2f, want in [%.2f, 1]", score, tt.wantGE)
+			}
+		})
+	}
+}
+
+func TestDuplicateScoreOrdersCloserMatchesFirst(t *testing.T) {
+	now := time.Now()
+	users := map[string]bool{"a": true, "b": true}
+	words := noteWords("cab to airport")
+	exact, _ := duplicateScore(users, words, detail("a", "cab to airport", now.Add(-time.Minute), "a", "b"), now)
+	later, _ := duplicateScore(users, words, detail("a", "cab to airport", now.Add(-3*time.Hour), "a", "b"), now)
+	partial, _ := duplicateScore(users, words, detail("a", "cab", now.Add(-time.Minute), "a", "b"), now)
+	if !(exact > later && exact > partial) {
+		t.Errorf("scores exact=%.2f later=%.2f partial=%.2f, want exact highest", exact, later, partial)
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	set := func(ks ...string) map[string]bool {
+		m := map[string]bool{}
+		for _, k := range ks {
+			m[k] = true
+		}
+		return m
+	}
+	tests := []struct {
+		a, b map[string]bool
+		want float64
+	}{
+		{set(), set(), 0},
+		{set("a"), set(), 0},
+		{set("a", "b"), set("a", "b"), 1},
+		{set("a", "b"), set("b", "c"), 1.0 / 3},
+		{set("a", "b", "c", "d"), set("a", "b"), 0.5},
+	}
+	for _, tt := range tests {
+		if got := jaccard(tt.a, tt.b); got != tt.want {
+			t.Errorf("jaccard(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMergeSplits(t *testing.T) {
+	split := func(pairs ...any) []types.ExpenseSplit {
+		var out []types.ExpenseSplit
+		for i := 0; i < len(pairs); i += 2 {
+			out = append(out, types.ExpenseSplit{UserID: pairs[i].(string), Exact: types.Money(pairs[i+1].(int))})
+		}
+		return out
+	}
+	tests := []struct {
+		name      string
+		amount    types.Money
+		cur, more []types.ExpenseSplit
+		want      []types.ExpenseSplit
+		added     int
+	}{
+		{"same people", 900, split("a", 300, "b", 300, "c", 300), split("b", 450, "a", 450), split("a", 300, "b", 300, "c", 300), 0},
+		{"equal stays equal", 1000, split("a", 334, "b", 333, "c", 333), split("a", 0, "d", 0), split("a", 250, "b", 250, "c", 250, "d", 250), 1},
+		{"weights kept", 1200, split("a", 600, "b", 200), split("c", 0), split("a", 600, "b", 200, "c", 400), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, added, err := mergeSplits(tt.amount, tt.cur, tt.more)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(added) != tt.added {
+				t.Errorf("added %v, want %d users", added, tt.added)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			var sum types.Money
+			for i := range got {
+				sum += got[i].Exact
+				if got[i] != tt.want[i] {
+					t.Errorf("split %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+			if sum != tt.amount {
+				t.Errorf("splits sum to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Every error response is RFC 7807 problem+json:
+//
+//	{"type": "about:blank", "title": "Not Found", "status": 404,
+//	 "code": "not_found", "detail": "expense not found", ...}
+//
+// code is stable and what clients should switch on; detail is for humans.
+
+const mimeProblemJSON = "application/problem+json"
+
+// localsError holds a 5xx's error for AccessLogMiddleware to log.
+const localsError = "error"
+
+// errInvalidJSON is a body that doesn't parse, a plain 400 as there are no
+// fields to point at yet. Values that parse but are wrong are returned as a
+// db.ValidationError: 422 validation_failed with the fields listed.
+var errInvalidJSON = fiber.NewError(http.StatusBadRequest, "invalid JSON body")
+
+// Problem is an error with its response already decided, for the cases
+// where a handler wants a specific code or extra members in the body.
+type Problem struct {
+	Status int
+	Code   string
+	Detail string
+	Extra  fiber.Map // extension members, e.g. "candidates"
+}
+
+func (p *Problem) Error() string { return p.Detail }
+
+// ErrorHandler is fiber's ErrorHandler: handlers return db domain errors,
+// fiber.NewError or a *Problem and this writes the response. Anything else
+// is a 500 whose detail only goes to the access log.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	p := toProblem(err)
+	if p.Status >= 500 {
+		c.Locals(localsError, err)
+	}
+
+	body := fiber.Map{
+		"type":     "about:blank",
+		"title":    http.StatusText(p.Status),
+		"status":   p.Status,
+		"code":     p.Code,
+		"instance": c.Path(),
+	}
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	if id, ok := c.Locals("request_id").(string); ok {
+		body["request_id"] = id
+	}
+	for k, v := range p.Extra {
+		body[k] = v
+	}
+	return c.Status(p.Status).JSON(body, mimeProblemJSON)
+}
+
+func toProblem(err error) *Problem {
+	var (
+		prob       *Problem
+		notFound   *db.NotFoundError
+		conflict   *db.ConflictError
+		invalid    *db.ValidationError
+		forbidden  *db.ForbiddenError
+		fiberError *fiber.Error
+	)
+	switch {
+	case errors.As(err, &prob):
+		return prob
+	case errors.As(err, &notFound):
+		return &Problem{Status: http.StatusNotFound, Code: "not_found", Detail: notFound.Error()}
+	case errors.Is(err, db.ErrVersionConflict):
+		return &Problem{Status: http.StatusPreconditionFailed, Code: "version_conflict", Detail: err.Error()}
+	case errors.As(err, &conflict):
+		return &Problem{Status: http.StatusConflict, Code: conflict.Code, Detail: conflict.Message}
+	case errors.As(err, &invalid):
+		return &Problem{Status: http.StatusUnprocessableEntity, Code: "validation_failed", Detail: invalid.Error(),
+			Extra: fiber.Map{"errors": invalid.Fields}}
+	case errors.As(err, &forbidden):
+		return &Problem{Status: http.StatusForbidden, Code: "forbidden", Detail: forbidden.Message}
+	case errors.As(err, &fiberError):
+		return &Problem{Status: fiberError.Code, Code: statusCode(fiberError.Code), Detail: fiberError.Message}
+	}
+	return &Problem{Status: http.StatusInternalServerError, Code: "internal", Detail: "internal error"}
+}
+
+// statusCode is the code for errors that only carry a status, e.g.
+// 404 -> "not_found".
+func statusCode(status int) string {
+	switch status {
+	case http.StatusInternalServerError:
+		return "internal"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	case http.StatusUnprocessableEntity:
+		return "unprocessable"
+	case http.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// The ETag of a user, group or expense is just its version number.
+
+func setETag(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// ifMatch reads the version the client last saw from If-Match, which is
+// required on PATCH/DELETE of versioned resources. "*" matches whatever is
+// current. A stale tag is db.ErrVersionConflict, same as when the store
+// catches it.
+func ifMatch(c *fiber.Ctx, current int64) (int64, error) {
+	v := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if v == "" {
+		return 0, fiber.NewError(http.StatusPreconditionRequired, "If-Match header with the resource's ETag is required")
+	}
+	if v == "*" {
+		return current, nil
+	}
+	// we don't send weak tags but some clients add W/ anyway
+	v = strings.Trim(strings.TrimPrefix(v, "W/"), `"`)
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fiber.NewError(http.StatusBadRequest, "If-Match must be an ETag from this API")
+	}
+	if version != current {
+		setETag(c, current) // so the client knows what it is up against
+		return 0, db.ErrVersionConflict
+	}
+	return version, nil
+}
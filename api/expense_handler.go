@@ -1,102 +1,597 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/akarshgo/paysplit/blob"
+	"github.com/akarshgo/paysplit/categorize"
 	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/metrics"
 	"github.com/akarshgo/paysplit/types"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 // Wire this with your db.ExpenseStore
 type ExpenseHandlers struct {
-	expenses db.ExpenseStore
+	expenses    db.ExpenseStore
+	groups      db.GroupStore // custom categories, members
+	attachments db.AttachmentStore
+	blobs       blob.Store // attachment files, cleaned up on delete
+	categorizer *categorize.Categorizer
+	feed        *ActivityRecorder
+	// CheckDuplicates turns on the possible_duplicate warning on create
+	CheckDuplicates bool
 }
 
-func NewExpenseHandlers(exp db.ExpenseStore) *ExpenseHandlers {
-	return &ExpenseHandlers{expenses: exp}
+func NewExpenseHandlers(exp db.ExpenseStore, groups db.GroupStore, attachments db.AttachmentStore, blobs blob.Store, feed *ActivityRecorder) *ExpenseHandlers {
+	return &ExpenseHandlers{
+		expenses:    exp,
+		groups:      groups,
+		attachments: attachments,
+		blobs:       blobs,
+		categorizer: categorize.Default(),
+		feed:        feed,
+
+		CheckDuplicates: true,
+	}
 }
 
 // ---------- CREATE EXPENSE ----------
 
 type createExpenseReq struct {
-	PaidBy string           `json:"paid_by"`
-	Note   string           `json:"note"`
-	Amount int64            `json:"amount_paise"` // paise
-	Split  types.SplitInput `json:"split"`
+	PaidBy      string           `json:"paid_by"`
+	Note        string           `json:"note"`
+	Amount      types.Money      `json:"amount_paise"`           // paise
+	Category    types.Category   `json:"category,omitempty"`     // guessed when empty
+	MerchantVPA string           `json:"merchant_vpa,omitempty"` // helps the guess
+	Split       types.SplitInput `json:"split"`
+	// set after a possible_duplicate 409: force creates anyway, merge_into
+	// folds this one into the named existing expense (see mergeInto)
+	Force     bool   `json:"force,omitempty"`
+	MergeInto string `json:"merge_into,omitempty"`
 }
 
 func (h *ExpenseHandlers) HandleCreateExpense(c *fiber.Ctx) error {
 	groupID := c.Params("id")
 	var req createExpenseReq
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "invalid JSON"})
+		return errInvalidJSON
 	}
-	if groupID == "" || req.PaidBy == "" || req.Amount <= 0 || len(req.Split.Users) == 0 {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "missing fields"})
+	if req.PaidBy == "" {
+		return db.Invalid("paid_by", "required")
 	}
 
 	// 1) Normalize the split to exact amounts per user
 	splits, err := normalizeSplits(req.Amount, req.Split)
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		return err
+	}
+	if err := h.checkMembers(c.UserContext(), groupID, req.PaidBy, req.Split.Users); err != nil {
+		return err
+	}
+
+	// 2) Pick the category: validate the client's, or guess from note/merchant
+	category, err := h.category(c, groupID, req.Category, req.Note, req.MerchantVPA)
+	if err != nil {
+		return err
 	}
 
-	// 2) Build the expense row
+	// 3) Build the expense row
 	exp := &types.Expense{
 		GroupID:     groupID,
 		PaidBy:      req.PaidBy,
 		AmountPaise: req.Amount,
 		Currency:    "INR",
 		Note:        req.Note,
+		Category:    category,
+		MerchantVPA: strings.ToLower(strings.TrimSpace(req.MerchantVPA)),
 		SplitKind:   req.Split.Kind,
 	}
 
-	// 3) Persist (store will create expense + insert split rows in a TX)
-	id, err := h.expenses.Create(c.Context(), exp, splits)
+	// 4) Catch the same dinner logged twice
+	if req.MergeInto != "" {
+		return h.mergeInto(c, req.MergeInto, exp, splits)
+	}
+	if h.CheckDuplicates && !req.Force {
+		dups, err := h.findDuplicates(c.UserContext(), exp, splits, time.Now())
+		if err != nil {
+			return err
+		}
+		if len(dups) > 0 {
+			return &Problem{Status: http.StatusConflict, Code: "possible_duplicate", Detail: "possible duplicate expense",
+				Extra: fiber.Map{
+					"candidates": dups,
+					"hint":       "resend with force=true to create anyway, or merge_into=<id> with its ETag in If-Match to merge into that one",
+				}}
+		}
+	}
+
+	// 5) Persist (store will create expense + insert split rows in a TX)
+	id, err := h.expenses.Create(c.UserContext(), exp, splits)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create expense"})
+		return err
 	}
+	h.feed.record(c, groupID, types.ActionExpenseCreated, "expense", id, expenseSummary(exp))
+	metrics.ExpensesCreated.Inc()
+	metrics.ExpensePaise.Add(float64(exp.AmountPaise))
+	setETag(c, exp.Version)
 
 	return c.Status(http.StatusCreated).JSON(fiber.Map{"id": id})
 }
 
+// checkMembers is a 422 naming paidBy, unless empty, and each of users
+// that isn't in the group.
+func (h *ExpenseHandlers) checkMembers(ctx context.Context, groupID, paidBy string, users []types.SplitInputUser) error {
+	members, err := groupMembers(ctx, h.groups, groupID)
+	if err != nil {
+		return err
+	}
+	var invalid db.ValidationError
+	if paidBy != "" && !members[paidBy] {
+		invalid.Add("paid_by", notMember)
+	}
+	for i, u := range users {
+		if !members[u.UserID] {
+			invalid.Add(fmt.Sprintf("split.users[%d].user_id", i), notMember)
+		}
+	}
+	return invalid.Err()
+}
+
+// mergeInto folds a resubmitted expense into the existing one it duplicates:
+// people only the new one had join the split, shares are respread over the
+// same total and its note is appended. Like a PATCH it needs the existing
+// expense's ETag in If-Match, and it is one Update, so the ledger and audit
+// log move with it and an edit since gets a version_conflict.
+func (h *ExpenseHandlers) mergeInto(c *fiber.Ctx, id string, dup *types.Expense, dupSplits []types.ExpenseSplit) error {
+	cur, err := h.expenses.Get(c.UserContext(), dup.GroupID, id)
+	if err != nil {
+		return err
+	}
+	version, err := ifMatch(c, cur.Version)
+	if err != nil {
+		return err
+	}
+	if cur.AmountPaise != dup.AmountPaise {
+		return db.Invalid("merge_into", "expense has a different amount")
+	}
+	if cur.PaidBy != dup.PaidBy {
+		return db.Invalid("merge_into", "expense was paid by someone else")
+	}
+
+	exp := cur.Expense
+	exp.Version = version
+	splits := make([]types.ExpenseSplit, len(cur.Splits))
+	for i, sp := range cur.Splits {
+		splits[i] = sp.ExpenseSplit
+	}
+	splits, added, err := mergeSplits(exp.AmountPaise, splits, dupSplits)
+	if err != nil {
+		return err
+	}
+	if len(added) > 0 && exp.SplitKind != types.SplitEqual {
+		exp.SplitKind = types.SplitShares // no longer the exact amounts or percentages anyone entered
+	}
+	if note := strings.TrimSpace(dup.Note); note != "" && !strings.Contains(strings.ToLower(exp.Note), strings.ToLower(note)) {
+		if exp.Note != "" {
+			note = exp.Note + "; " + note
+		}
+		exp.Note = note
+	}
+
+	if err := h.expenses.Update(c.UserContext(), &exp, splits); err != nil {
+		return err
+	}
+	if added == nil {
+		added = []string{}
+	}
+	h.feed.record(c, exp.GroupID, types.ActionExpenseMerged, "expense", exp.ID, fiber.Map{
+		"before":      expenseSummary(&cur.Expense),
+		"after":       expenseSummary(&exp),
+		"added_users": added,
+	})
+
+	out, err := h.expenses.Get(c.UserContext(), exp.GroupID, exp.ID)
+	if err != nil {
+		return err
+	}
+	setETag(c, out.Version)
+	return c.JSON(out)
+}
+
+// mergeSplits adds the users only in more to splits and respreads amount:
+// everyone already there keeps their weight and each newcomer gets the
+// average one, so an equal split stays equal. It returns who was added.
+func mergeSplits(amount types.Money, splits, more []types.ExpenseSplit) ([]types.ExpenseSplit, []string, error) {
+	have := make(map[string]bool, len(splits))
+	var total int64
+	for _, sp := range splits {
+		have[sp.UserID] = true
+		total += int64(sp.Exact)
+	}
+	var added []string
+	for _, sp := range more {
+		if !have[sp.UserID] {
+			have[sp.UserID] = true
+			added = append(added, sp.UserID)
+		}
+	}
+	if len(added) == 0 {
+		return splits, nil, nil
+	}
+
+	avg := int64(1)
+	if len(splits) > 0 {
+		avg = max(total/int64(len(splits)), 1)
+	}
+	weights := make([]int64, 0, len(splits)+len(added))
+	users := make([]string, 0, len(splits)+len(added))
+	for _, sp := range splits {
+		weights, users = append(weights, int64(sp.Exact)), append(users, sp.UserID)
+	}
+	for _, u := range added {
+		weights, users = append(weights, avg), append(users, u)
+	}
+	parts, err := amount.Allocate(weights)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make([]types.ExpenseSplit, len(users))
+	for i, u := range users {
+		out[i] = types.ExpenseSplit{UserID: u, Exact: parts[i]}
+	}
+	return out, added, nil
+}
+
+// category validates the client's category, or guesses one from note/merchant
+// when it is empty.
+func (h *ExpenseHandlers) category(c *fiber.Ctx, groupID string, requested types.Category, note, merchantVPA string) (types.Category, error) {
+	custom, err := h.groups.ListCategories(c.UserContext(), groupID)
+	if err != nil {
+		return "", err
+	}
+	category := types.Category(strings.ToLower(strings.TrimSpace(string(requested))))
+	if category == "" {
+		return h.categorizer.WithCustom(custom).Guess(note, merchantVPA), nil
+	}
+	if !validCategory(category, custom) {
+		return "", db.Invalid("category", "unknown category")
+	}
+	return category, nil
+}
+
+// expenseSummary is what the activity feed keeps about an expense
+func expenseSummary(e *types.Expense) fiber.Map {
+	return fiber.Map{"note": e.Note, "amount_paise": e.AmountPaise, "paid_by": e.PaidBy}
+}
+
 // ---------- LIST EXPENSES ----------
 
+// GET /groups/:id/expenses?from=&to=&paid_by=&participant=&category=&min_amount=&max_amount=&q=&limit=&cursor=&embed=splits
+// The next page's cursor comes back in the X-Next-Cursor header (absent on the last page).
+// embed=splits adds each expense's splits and payer/participant names.
 func (h *ExpenseHandlers) HandleListExpenses(c *fiber.Ctx) error {
 	groupID := c.Params("id")
-	out, err := h.expenses.ListByGroup(c.Context(), groupID)
+	f, err := expenseFilter(c)
+	if err != nil {
+		return err
+	}
+	limit, _ := parseLimitOffset(c.Query("limit"), "")
+
+	var (
+		out  any
+		next string
+	)
+	if c.Query("embed") == "splits" {
+		var list []*types.ExpenseDetail
+		list, next, err = h.expenses.ListDetailsByGroup(c.UserContext(), groupID, f, limit, c.Query("cursor"))
+		if list == nil {
+			list = []*types.ExpenseDetail{}
+		}
+		out = list
+	} else {
+		var list []*types.Expense
+		list, next, err = h.expenses.ListByGroup(c.UserContext(), groupID, f, limit, c.Query("cursor"))
+		if list == nil {
+			list = []*types.Expense{}
+		}
+		out = list
+	}
+	if errors.Is(err, db.ErrBadCursor) {
+		return db.Invalid("cursor", "invalid cursor")
+	}
+	if err != nil {
+		return err
+	}
+	if next != "" {
+		c.Set("X-Next-Cursor", next)
+	}
+	return c.JSON(out)
+}
+
+// ---------- GET EXPENSE ----------
+
+// GET /groups/:id/expenses/:eid
+func (h *ExpenseHandlers) HandleGetExpense(c *fiber.Ctx) error {
+	out, err := h.expenses.Get(c.UserContext(), c.Params("id"), c.Params("eid"))
+	if err != nil {
+		return err
+	}
+	setETag(c, out.Version)
+	return c.JSON(out)
+}
+
+// ---------- UPDATE EXPENSE ----------
+
+// Only the fields present are changed. A new amount without a new split is
+// re-spread in proportion to the current one; exact splits need a new split.
+type updateExpenseReq struct {
+	PaidBy      *string           `json:"paid_by"`
+	Note        *string           `json:"note"`
+	Amount      *types.Money      `json:"amount_paise"`
+	Category    *types.Category   `json:"category"`
+	MerchantVPA *string           `json:"merchant_vpa"`
+	Split       *types.SplitInput `json:"split"`
+}
+
+// PATCH /groups/:id/expenses/:eid
+func (h *ExpenseHandlers) HandleUpdateExpense(c *fiber.Ctx) error {
+	groupID := c.Params("id")
+	var req updateExpenseReq
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
+	}
+	cur, err := h.expenses.Get(c.UserContext(), groupID, c.Params("eid"))
+	if err != nil {
+		return err
+	}
+	version, err := ifMatch(c, cur.Version)
+	if err != nil {
+		return err
+	}
+
+	exp := cur.Expense
+	exp.Version = version
+	if req.PaidBy != nil {
+		if *req.PaidBy == "" {
+			return db.Invalid("paid_by", "must not be empty")
+		}
+		exp.PaidBy = *req.PaidBy
+	}
+	if req.PaidBy != nil || req.Split != nil {
+		var paidBy string
+		var users []types.SplitInputUser
+		if req.PaidBy != nil {
+			paidBy = *req.PaidBy
+		}
+		if req.Split != nil {
+			users = req.Split.Users
+		}
+		if err := h.checkMembers(c.UserContext(), groupID, paidBy, users); err != nil {
+			return err
+		}
+	}
+	if req.Note != nil {
+		exp.Note = *req.Note
+	}
+	if req.MerchantVPA != nil {
+		exp.MerchantVPA = strings.ToLower(strings.TrimSpace(*req.MerchantVPA))
+	}
+	if req.Amount != nil {
+		exp.AmountPaise = *req.Amount
+	}
+	if req.Category != nil {
+		if exp.Category, err = h.category(c, groupID, *req.Category, exp.Note, exp.MerchantVPA); err != nil {
+			return err
+		}
+	}
+
+	splits := make([]types.ExpenseSplit, len(cur.Splits))
+	for i, sp := range cur.Splits {
+		splits[i] = sp.ExpenseSplit
+	}
+	switch {
+	case req.Split != nil:
+		if splits, err = normalizeSplits(exp.AmountPaise, *req.Split); err != nil {
+			return err
+		}
+		exp.SplitKind = req.Split.Kind
+	case exp.AmountPaise != cur.AmountPaise:
+		if exp.SplitKind == types.SplitExact {
+			return db.Invalid("split", "required to change the amount of an exact split")
+		}
+		if splits, err = respread(exp.AmountPaise, splits); err != nil {
+			return err
+		}
+	}
+
+	if err := h.expenses.Update(c.UserContext(), &exp, splits); err != nil {
+		return err
+	}
+	h.feed.record(c, groupID, types.ActionExpenseUpdated, "expense", exp.ID, fiber.Map{
+		"before": expenseSummary(&cur.Expense),
+		"after":  expenseSummary(&exp),
+	})
+
+	out, err := h.expenses.Get(c.UserContext(), groupID, exp.ID)
+	if err != nil {
+		return err
+	}
+	setETag(c, out.Version)
+	return c.JSON(out)
+}
+
+// respread keeps everyone's proportion of the old split for a new amount.
+func respread(amount types.Money, splits []types.ExpenseSplit) ([]types.ExpenseSplit, error) {
+	if amount <= 0 {
+		return nil, db.Invalid("amount_paise", "must be > 0")
+	}
+	weights := make([]int64, len(splits))
+	for i, sp := range splits {
+		weights[i] = int64(sp.Exact)
+	}
+	parts, err := amount.Allocate(weights)
+	if err != nil {
+		return nil, db.Invalid("amount_paise", err.Error())
+	}
+	out := make([]types.ExpenseSplit, len(splits))
+	for i, sp := range splits {
+		out[i] = types.ExpenseSplit{UserID: sp.UserID, Exact: parts[i]}
+	}
+	return out, nil
+}
+
+// ---------- DELETE EXPENSE ----------
+
+// DELETE /groups/:id/expenses/:eid
+func (h *ExpenseHandlers) HandleDeleteExpense(c *fiber.Ctx) error {
+	groupID, expenseID := c.Params("id"), c.Params("eid")
+	cur, err := h.expenses.Get(c.UserContext(), groupID, expenseID)
+	if err != nil {
+		return err
+	}
+	version, err := ifMatch(c, cur.Version)
+	if err != nil {
+		return err
+	}
+
+	// grab the blob keys first; the rows go away with the expense (ON DELETE CASCADE)
+	files, err := h.attachments.ListByExpense(c.UserContext(), expenseID)
+	if err != nil {
+		return err
+	}
+	if err := h.expenses.Delete(c.UserContext(), groupID, expenseID, version); err != nil {
+		return err
+	}
+	removeAttachmentBlobs(c.UserContext(), h.blobs, files)
+	h.feed.record(c, groupID, types.ActionExpenseDeleted, "expense", expenseID, expenseSummary(&cur.Expense))
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// ---------- REPORTS ----------
+
+// GET /groups/:id/reports/categories
+func (h *ExpenseHandlers) HandleCategoryReport(c *fiber.Ctx) error {
+	groupID := c.Params("id")
+	f, err := expenseFilter(c)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list expenses"})
+		return err
+	}
+	out, err := h.expenses.TotalsByCategory(c.UserContext(), groupID, f)
+	if err != nil {
+		return err
 	}
 	return c.JSON(out)
 }
 
+func expenseFilter(c *fiber.Ctx) (db.ExpenseFilter, error) {
+	var f db.ExpenseFilter
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := strings.TrimSpace(c.Query(p.name)); v != "" {
+			t, dateOnly, err := parseQueryTime(v)
+			if err != nil {
+				return f, db.Invalid(p.name, "must be RFC3339 or YYYY-MM-DD")
+			}
+			if dateOnly && p.name == "to" {
+				t = t.AddDate(0, 0, 1) // "to" is exclusive, a bare date means the whole day
+			}
+			*p.dst = &t
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **types.Money
+	}{{"min_amount", &f.MinAmount}, {"max_amount", &f.MaxAmount}} {
+		if v := strings.TrimSpace(c.Query(p.name)); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return f, db.Invalid(p.name, "must be non-negative paise")
+			}
+			m := types.Money(n)
+			*p.dst = &m
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **string
+	}{{"paid_by", &f.PaidBy}, {"participant", &f.Participant}} {
+		if v := strings.TrimSpace(c.Query(p.name)); v != "" {
+			if _, err := uuid.Parse(v); err != nil {
+				return f, db.Invalid(p.name, "must be a user id")
+			}
+			*p.dst = &v
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(c.Query("category"))); v != "" {
+		cat := types.Category(v)
+		f.Category = &cat
+	}
+	if v := strings.TrimSpace(c.Query("q")); v != "" {
+		f.Search = &v
+	}
+	return f, nil
+}
+
+// parseQueryTime accepts RFC3339 or a bare date (midnight IST).
+func parseQueryTime(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	t, err = time.ParseInLocation("2006-01-02", v, types.IST)
+	return t, true, err
+}
+
+func validCategory(c types.Category, custom []*types.GroupCategory) bool {
+	if c.IsBuiltin() {
+		return true
+	}
+	for _, gc := range custom {
+		if gc.Name == c {
+			return true
+		}
+	}
+	return false
+}
+
 // ---------- BALANCES & SIMPLIFY (OPTIONAL BONUS) ----------
 
 func (h *ExpenseHandlers) HandleGroupBalances(c *fiber.Ctx) error {
 	groupID := c.Params("id")
-	net, err := h.expenses.Balances(c.Context(), groupID) // map[userID]int64
+	net, err := h.expenses.Balances(c.UserContext(), groupID) // map[userID]paise
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to compute balances"})
+		return err
 	}
 	return c.JSON(net)
 }
 
 type transfer struct {
-	From   string `json:"from"`
-	To     string `json:"to"`
-	Amount int64  `json:"amount_paise"`
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Amount types.Money `json:"amount_paise"`
 }
 
 func (h *ExpenseHandlers) HandleSimplifyDebts(c *fiber.Ctx) error {
 	groupID := c.Params("id")
-	net, err := h.expenses.Balances(c.Context(), groupID)
+	net, err := h.expenses.Balances(c.UserContext(), groupID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to compute"})
+		return err
 	}
+	return c.JSON(simplifyDebts(net))
+}
 
+// simplifyDebts greedily pairs debtors with creditors so the group settles
+// in at most n-1 transfers.
+func simplifyDebts(net map[string]types.Money) []transfer {
 	var pos, neg [][2]any // [id, amt]
 	for id, v := range net {
 		if v > 0 {
@@ -109,8 +604,8 @@ func (h *ExpenseHandlers) HandleSimplifyDebts(c *fiber.Ctx) error {
 	i, j := 0, 0
 	tx := []transfer{}
 	for i < len(pos) && j < len(neg) {
-		p := pos[i][1].(int64)
-		n := neg[j][1].(int64)
+		p := pos[i][1].(types.Money)
+		n := neg[j][1].(types.Money)
 		pay := p
 		if n < p {
 			pay = n
@@ -127,95 +622,95 @@ func (h *ExpenseHandlers) HandleSimplifyDebts(c *fiber.Ctx) error {
 			j++
 		}
 	}
-	return c.JSON(tx)
+	return tx
 }
 
 // ---------- NORMALIZATION LOGIC ----------
 // Convert client SplitInput into []ExpenseSplit with exact paise per user.
 // Guarantees: len(users)>0, sum(exact) == amount, handles rounding safely.
+// Bad input is a *db.ValidationError naming the fields, e.g. split.users[1].shares.
 
-func normalizeSplits(amount int64, in types.SplitInput) ([]types.ExpenseSplit, error) {
+func normalizeSplits(amount types.Money, in types.SplitInput) ([]types.ExpenseSplit, error) {
+	var invalid db.ValidationError
 	if amount <= 0 {
-		return nil, fmt.Errorf("amount must be > 0")
+		invalid.Add("amount_paise", "must be > 0")
 	}
 	if len(in.Users) == 0 {
-		return nil, fmt.Errorf("at least one user required")
+		invalid.Add("split.users", "at least one user required")
+	}
+	if err := invalid.Err(); err != nil {
+		return nil, err
+	}
+	userField := func(i int, name string) string {
+		return fmt.Sprintf("split.users[%d].%s", i, name)
 	}
 
+	var parts []types.Money
+	var err error
 	switch in.Kind {
 	case types.SplitEqual:
-		share := amount / int64(len(in.Users))
-		rem := amount - share*int64(len(in.Users))
-		out := make([]types.ExpenseSplit, 0, len(in.Users))
-		for i, u := range in.Users {
-			o := share
-			if int64(i) < rem {
-				o++
-			} // distribute remainder paise fairly
-			out = append(out, types.ExpenseSplit{UserID: u.UserID, Exact: types.Money(o)})
-		}
-		return out, nil
+		// remainder paise go to the first users
+		parts, err = amount.Split(len(in.Users))
 
 	case types.SplitShares:
-		var sum int64
-		for _, u := range in.Users {
+		weights := make([]int64, len(in.Users))
+		for i, u := range in.Users {
 			if u.Shares == nil || *u.Shares <= 0 {
-				return nil, fmt.Errorf("shares required and must be > 0")
+				invalid.Add(userField(i, "shares"), "required and must be > 0")
+				continue
 			}
-			sum += *u.Shares
+			weights[i] = *u.Shares
 		}
-		var acc int64
-		out := make([]types.ExpenseSplit, len(in.Users))
-		for i, u := range in.Users {
-			o := (amount * *u.Shares) / sum
-			out[i] = types.ExpenseSplit{UserID: u.UserID, Exact: types.Money(o)}
-			acc += o
-			if i == len(in.Users)-1 { // last gets remainder
-				out[i].Exact += types.Money(amount - acc)
-			}
+		if invalid.Err() == nil {
+			parts, err = amount.Allocate(weights)
 		}
-		return out, nil
 
 	case types.SplitPercent:
+		weights := make([]int64, len(in.Users))
 		var sum int64
-		for _, u := range in.Users {
-			if u.PercentBP == nil || *u.PercentBP < 0 {
-				return nil, fmt.Errorf("percent_bp required")
+		for i, u := range in.Users {
+			if u.PercentBP == nil || *u.PercentBP < 0 || *u.PercentBP > 10000 {
+				invalid.Add(userField(i, "percent_bp"), "required, 0-10000")
+				continue
 			}
+			weights[i] = *u.PercentBP
 			sum += *u.PercentBP
 		}
-		if sum != 10000 {
-			return nil, fmt.Errorf("percent total must be exactly 10000 basis points")
+		if invalid.Err() == nil && sum != 10000 {
+			invalid.Add("split.users", "percent_bp must total exactly 10000 basis points")
 		}
-		var acc int64
-		out := make([]types.ExpenseSplit, len(in.Users))
-		for i, u := range in.Users {
-			o := (amount * *u.PercentBP) / 10000
-			out[i] = types.ExpenseSplit{UserID: u.UserID, Exact: types.Money(o)}
-			acc += o
-			if i == len(in.Users)-1 {
-				out[i].Exact += types.Money(amount - acc)
-			}
+		if invalid.Err() == nil {
+			parts, err = amount.Allocate(weights)
 		}
-		return out, nil
 
 	case types.SplitExact:
-		var acc int64
-		for _, u := range in.Users {
+		parts = make([]types.Money, len(in.Users))
+		for i, u := range in.Users {
 			if u.Exact == nil || *u.Exact < 0 {
-				return nil, fmt.Errorf("exact required and must be >= 0")
+				invalid.Add(userField(i, "exact"), "required and must be >= 0")
+				continue
 			}
-			acc += *u.Exact
+			parts[i] = *u.Exact
 		}
-		if acc != amount {
-			return nil, fmt.Errorf("sum of exact parts must equal amount")
-		}
-		out := make([]types.ExpenseSplit, len(in.Users))
-		for i, u := range in.Users {
-			out[i] = types.ExpenseSplit{UserID: u.UserID, Exact: types.Money(*u.Exact)}
+		if invalid.Err() == nil {
+			if total, err := types.Sum(parts...); err != nil || total != amount {
+				invalid.Add("split.users", "exact parts must sum to amount_paise")
+			}
 		}
-		return out, nil
+
+	default:
+		invalid.Add("split.kind", "must be equal, shares, percent or exact")
+	}
+	if err != nil {
+		invalid.Add("split", err.Error())
+	}
+	if err := invalid.Err(); err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("unknown split kind: %s", in.Kind)
+	out := make([]types.ExpenseSplit, len(in.Users))
+	for i, u := range in.Users {
+		out[i] = types.ExpenseSplit{UserID: u.UserID, Exact: parts[i]}
+	}
+	return out, nil
 }
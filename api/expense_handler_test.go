@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/types"
+)
+
+// FuzzNormalizeSplits builds a split of each kind from the fuzzed bytes,
+// one user per byte, and checks that whatever comes back either sums to
+// the amount or is a validation error.
+func FuzzNormalizeSplits(f *testing.F) {
+	f.Add(int64(1000), uint8(0), []byte{1, 1, 1})
+	f.Add(int64(1001), uint8(1), []byte{1, 2, 3})
+	f.Add(int64(99), uint8(2), []byte{50, 25, 25})
+	f.Add(int64(12345), uint8(3), []byte{7, 0, 9})
+	f.Add(int64(1), uint8(1), []byte{255, 255, 255, 255})
+	f.Add(int64(0), uint8(0), []byte{1})
+	f.Add(int64(1<<62), uint8(1), []byte{200, 1})
+
+	kinds := []types.SplitKind{types.SplitEqual, types.SplitShares, types.SplitPercent, types.SplitExact, "halves"}
+	f.Fuzz(func(t *testing.T, amount int64, k uint8, weights []byte) {
+		if len(weights) > 64 {
+			weights = weights[:64]
+		}
+		in := types.SplitInput{Kind: kinds[int(k)%len(kinds)], Users: make([]types.SplitInputUser, len(weights))}
+		var total int64
+		for _, w := range weights {
+			total += int64(w)
+		}
+		// percent and exact parts are scaled to the whole so most inputs
+		// are valid; the remainder goes to the first user
+		var bpLeft, exactLeft int64 = 10000, amount
+		for i, w := range weights {
+			u := types.SplitInputUser{UserID: fmt.Sprintf("u%d", i)}
+			shares := int64(w)
+			u.Shares = &shares
+			var bp int64
+			var exact types.Money
+			if total > 0 && i > 0 {
+				bp = 10000 * int64(w) / total
+				exact = types.Money(amount / total * int64(w))
+			}
+			bpLeft -= bp
+			exactLeft -= int64(exact)
+			u.PercentBP, u.Exact = &bp, &exact
+			in.Users[i] = u
+		}
+		if len(weights) > 0 {
+			*in.Users[0].PercentBP = bpLeft
+			*in.Users[0].Exact = types.Money(exactLeft)
+		}
+
+		out, err := normalizeSplits(types.Money(amount), in)
+		if err != nil {
+			var invalid *db.ValidationError
+			if !errors.As(err, &invalid) || len(invalid.Fields) == 0 {
+				t.Fatalf("error %v (%T) is not a validation error", err, err)
+			}
+			return
+		}
+		if len(out) != len(in.Users) {
+			t.Fatalf("%d splits for %d users", len(out), len(in.Users))
+		}
+		var sum types.Money
+		for i, s := range out {
+			if s.UserID != in.Users[i].UserID {
+				t.Errorf("split %d is for %s, want %s", i, s.UserID, in.Users[i].UserID)
+			}
+			if s.Exact < 0 {
+				t.Errorf("split %d is %d paise", i, s.Exact)
+			}
+			sum += s.Exact
+		}
+		if sum != types.Money(amount) {
+			t.Fatalf("%s splits sum to %d, want %d: %+v", in.Kind, sum, amount, out)
+		}
+	})
+}
+
+func TestSimplifyDebtsZeroesBalances(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 1000; round++ {
+		// random nets that sum to zero, as group balances do; some people
+		// are already settled
+		n := 1 + rng.Intn(12)
+		net := map[string]types.Money{}
+		var sum types.Money
+		for i := 1; i < n; i++ {
+			v := types.Money(rng.Int63n(200001) - 100000)
+			if rng.Intn(5) == 0 {
+				v = 0
+			}
+			net[fmt.Sprintf("u%d", i)] = v
+			sum += v
+		}
+		net["u0"] = -sum
+
+		left := map[string]types.Money{}
+		for id, v := range net {
+			left[id] = v
+		}
+		tx := simplifyDebts(net)
+		if len(tx) > max(n-1, 0) {
+			t.Errorf("round %d: %d transfers for %d people", round, len(tx), n)
+		}
+		for _, x := range tx {
+			if x.Amount <= 0 || x.From == x.To {
+				t.Fatalf("round %d: bad transfer %+v", round, x)
+			}
+			left[x.From] += x.Amount
+			left[x.To] -= x.Amount
+		}
+		for id, v := range left {
+			if v != 0 {
+				t.Fatalf("round %d: %s is left at %d after %+v (nets %v)", round, id, v, tx, net)
+			}
+		}
+	}
+}
@@ -1,7 +1,8 @@
 package api
 
 import (
-	"net/http"
+	"context"
+	"strings"
 
 	"github.com/akarshgo/paysplit/db"
 	"github.com/akarshgo/paysplit/types"
@@ -10,11 +11,13 @@ import (
 
 type GroupHandlers struct {
 	groups db.GroupStore
+	feed   *ActivityRecorder
 }
 
-func NewGroupHanlders(groups db.GroupStore) *GroupHandlers {
+func NewGroupHanlders(groups db.GroupStore, feed *ActivityRecorder) *GroupHandlers {
 	return &GroupHandlers{
 		groups: groups,
+		feed:   feed,
 	}
 }
 
@@ -25,17 +28,67 @@ type createGroupReq struct {
 
 func (h *GroupHandlers) HandleCreateGroup(c *fiber.Ctx) error {
 	var req createGroupReq
-	if err := c.BodyParser(&req); err != nil || req.Name == "" || req.CreatedBy == "" {
-		return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": "bad request"})
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
 	}
-	id, err := h.groups.Create(c.Context(), &types.Group{Name: req.Name, CreatedBy: req.CreatedBy})
+	var invalid db.ValidationError
+	if req.Name == "" {
+		invalid.Add("name", "required")
+	}
+	if req.CreatedBy == "" {
+		invalid.Add("created_by", "required")
+	}
+	if err := invalid.Err(); err != nil {
+		return err
+	}
+	id, err := h.groups.Create(c.UserContext(), &types.Group{Name: req.Name, CreatedBy: req.CreatedBy})
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to create"})
+		return err
 	}
-	_ = h.groups.AddMember(c.Context(), id, req.CreatedBy) // creator joins
+	h.feed.recordAs(c, req.CreatedBy, id, types.ActionGroupCreated, "group", id, fiber.Map{"name": req.Name})
 	return c.Status(201).JSON(fiber.Map{"id": id})
 }
 
+// GET /groups/:id
+func (h *GroupHandlers) HandleGetGroup(c *fiber.Ctx) error {
+	g, err := h.groups.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	setETag(c, g.Version)
+	return c.JSON(g)
+}
+
+type updateGroupReq struct {
+	Name string `json:"name"`
+}
+
+// PATCH /groups/:id (If-Match required)
+func (h *GroupHandlers) HandleUpdateGroup(c *fiber.Ctx) error {
+	var req updateGroupReq
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return db.Invalid("name", "required")
+	}
+	g, err := h.groups.Get(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	version, err := ifMatch(c, g.Version)
+	if err != nil {
+		return err
+	}
+
+	g.Name, g.Version = strings.TrimSpace(req.Name), version
+	if err := h.groups.Update(c.UserContext(), g); err != nil {
+		return err
+	}
+	setETag(c, g.Version)
+	return c.JSON(g)
+}
+
 type addMemberReq struct {
 	UserID string `json:"user_id"`
 }
@@ -43,20 +96,86 @@ type addMemberReq struct {
 func (h *GroupHandlers) HandleAddMember(c *fiber.Ctx) error {
 	gid := c.Params("id")
 	var req addMemberReq
-	if err := c.BodyParser(&req); err != nil || gid == "" || req.UserID == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "bad request"})
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
 	}
-	if err := h.groups.AddMember(c.Context(), gid, req.UserID); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to add"})
+	if req.UserID == "" {
+		return db.Invalid("user_id", "required")
 	}
+	if err := h.groups.AddMember(c.UserContext(), gid, req.UserID); err != nil {
+		return err
+	}
+	h.feed.record(c, gid, types.ActionMemberAdded, "user", req.UserID, nil)
 	return c.SendStatus(204)
 }
 
 func (h *GroupHandlers) HandleListGroups(c *fiber.Ctx) error {
 	// If your interface supports ListByUser(userID), you can pass a query param later.
-	out, err := h.groups.ListByUser(c.Context(), "")
+	out, err := h.groups.ListByUser(c.UserContext(), "")
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to list"})
+		return err
 	}
 	return c.JSON(out)
 }
+
+type addCategoryReq struct {
+	Name     string   `json:"name"`
+	Keywords []string `json:"keywords"`
+}
+
+// POST /groups/:id/categories (re-posting a name replaces its keywords)
+func (h *GroupHandlers) HandleAddCategory(c *fiber.Ctx) error {
+	gid := c.Params("id")
+	var req addCategoryReq
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
+	}
+	name := types.Category(strings.ToLower(strings.TrimSpace(req.Name)))
+	if name == "" {
+		return db.Invalid("name", "required")
+	}
+	if name.IsBuiltin() {
+		return db.Invalid("name", "must not be a built-in category")
+	}
+	keywords := make([]string, 0, len(req.Keywords))
+	for _, k := range req.Keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	gc := &types.GroupCategory{GroupID: gid, Name: name, Keywords: keywords}
+	if err := h.groups.AddCategory(c.UserContext(), gc); err != nil {
+		return err
+	}
+	return c.Status(201).JSON(gc)
+}
+
+// GET /groups/:id/categories — built-in plus the group's own
+func (h *GroupHandlers) HandleListCategories(c *fiber.Ctx) error {
+	custom, err := h.groups.ListCategories(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	if custom == nil {
+		custom = []*types.GroupCategory{}
+	}
+	return c.JSON(fiber.Map{"builtin": types.BuiltinCategories, "custom": custom})
+}
+
+// notMember is the 422 message for a user ID that isn't in the group.
+const notMember = "must be a member of the group"
+
+// groupMembers is the set of the group's user IDs. Stores only check that a
+// user exists, so handlers check the users a request names against it
+// before writing.
+func groupMembers(ctx context.Context, groups db.GroupStore, groupID string) (map[string]bool, error) {
+	ids, err := groups.ListMembers(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		members[id] = true
+	}
+	return members, nil
+}
@@ -1,12 +1,91 @@
 package api
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
-//HanldeHealth returns a simple ok response
-//useful for uptime checks, monitoring and debugging
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
 
-func HandleHealth(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status": "ok",
-	})
+// how long /readyz waits on each dependency
+const probeTimeout = 2 * time.Second
+
+// HealthHandlers serves the probes. /livez only says the process is up;
+// /readyz says whether it should get traffic: Postgres has to answer, Redis
+// is optional (idempotency, caching and rate limits degrade without it) so
+// it is reported but doesn't fail the check.
+type HealthHandlers struct {
+	db       *sql.DB
+	rdb      *redis.Client
+	draining atomic.Bool
+}
+
+func NewHealthHandlers(sqlDB *sql.DB, rdb *redis.Client) *HealthHandlers {
+	return &HealthHandlers{db: sqlDB, rdb: rdb}
+}
+
+// Drain makes /readyz fail from now on, so load balancers stop routing
+// here before the server shuts down.
+func (h *HealthHandlers) Drain() { h.draining.Store(true) }
+
+// GET /livez
+func (h *HealthHandlers) HandleLivez(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// GET /readyz, also /v1/health
+func (h *HealthHandlers) HandleReadyz(c *fiber.Ctx) error {
+	if h.draining.Load() {
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"status": "draining"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.UserContext(), probeTimeout)
+	defer cancel()
+	var dbErr, redisErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		dbErr = h.db.PingContext(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		redisErr = h.rdb.Ping(ctx).Err()
+	}()
+	wg.Wait()
+
+	checks := fiber.Map{"postgres": checkResult(dbErr), "redis": checkResult(redisErr)}
+	switch {
+	case dbErr != nil:
+		return c.Status(http.StatusServiceUnavailable).JSON(fiber.Map{"status": "unavailable", "checks": checks})
+	case redisErr != nil:
+		return c.JSON(fiber.Map{"status": "degraded", "checks": checks})
+	}
+	return c.JSON(fiber.Map{"status": "ok", "checks": checks})
+}
+
+// the probe is unauthenticated, so no error text (addresses, users)
+func checkResult(err error) string {
+	if err != nil {
+		return "down"
+	}
+	return "ok"
+}
+
+// GET /ping-redis round-trips a key through Redis
+func (h *HealthHandlers) HandlePingRedis(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	if err := h.rdb.Set(ctx, "paysplit:ping", "pong", 0).Err(); err != nil {
+		return err
+	}
+	val, err := h.rdb.Get(ctx, "paysplit:ping").Result()
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"redis": "ok", "val": val})
 }
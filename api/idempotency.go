@@ -0,0 +1,131 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/akarshgo/paysplit/logger"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	maxIdempotencyKey = 255
+	// how long a key is held while its first request is still running
+	idempotencyLockTTL = time.Minute
+)
+
+// headers worth replaying along with the body
+var replayHeaders = []string{fiber.HeaderContentType, fiber.HeaderLocation, fiber.HeaderETag, "X-Next-Cursor"}
+
+type idempotencyRecord struct {
+	Hash    string            `json:"hash"`
+	Status  int               `json:"status"` // 0 while the first request is in flight
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    []byte            `json:"body,omitempty"`
+}
+
+// IdempotencyMiddleware makes POST/PATCH/PUT/DELETE safe to retry. When the
+// client sends an Idempotency-Key, the first response is kept in Redis for
+// ttl and replayed for any retry with the same key and body; the same key
+// with a different body is a 422. 5xx responses aren't kept so they can be
+// retried for real. Without Redis requests go through unprotected.
+func IdempotencyMiddleware(rdb *redis.Client, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(idempotencyHeader)
+		if key == "" || rdb == nil {
+			return c.Next()
+		}
+		switch c.Method() {
+		case fiber.MethodPost, fiber.MethodPatch, fiber.MethodPut, fiber.MethodDelete:
+		default:
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKey {
+			return fiber.NewError(http.StatusBadRequest, "Idempotency-Key too long")
+		}
+
+		ctx := c.UserContext()
+		sum := sha256.New()
+		sum.Write([]byte(c.Method() + " " + c.OriginalURL() + "\n"))
+		sum.Write(c.Body())
+		hash := hex.EncodeToString(sum.Sum(nil))
+		// keys are per caller, two users may well pick the same one
+		rkey := "paysplit:idem:" + actorID(c) + ":" + key
+
+		pending, _ := json.Marshal(idempotencyRecord{Hash: hash})
+		ok, err := rdb.SetNX(ctx, rkey, pending, idempotencyLockTTL).Result()
+		if err != nil {
+			logger.From(ctx).Warn("idempotency: redis unavailable", zap.Error(err))
+			return c.Next()
+		}
+		if !ok {
+			return replay(c, rdb, rkey, hash)
+		}
+
+		// handlers return errors; write the problem response now so a 4xx
+		// is kept like any other response
+		if err := c.Next(); err != nil {
+			if err := c.App().Config().ErrorHandler(c, err); err != nil {
+				rdb.Del(ctx, rkey)
+				return err
+			}
+		}
+
+		status := c.Response().StatusCode()
+		if status >= 500 {
+			rdb.Del(ctx, rkey)
+			return nil
+		}
+		rec := idempotencyRecord{Hash: hash, Status: status, Headers: map[string]string{}, Body: c.Response().Body()}
+		for _, h := range replayHeaders {
+			if v := c.GetRespHeader(h); v != "" {
+				rec.Headers[h] = v
+			}
+		}
+		b, err := json.Marshal(rec)
+		if err == nil {
+			err = rdb.Set(ctx, rkey, b, ttl).Err()
+		}
+		if err != nil {
+			logger.From(ctx).Warn("idempotency: failed to save response", zap.String("key", key), zap.Error(err))
+		}
+		return nil
+	}
+}
+
+func replay(c *fiber.Ctx, rdb *redis.Client, rkey, hash string) error {
+	b, err := rdb.Get(c.UserContext(), rkey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// expired between SETNX and GET; make the client try again
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return fiber.NewError(http.StatusConflict, "request with this Idempotency-Key is in progress")
+	}
+	if err != nil {
+		return fiber.NewError(http.StatusServiceUnavailable, "could not check Idempotency-Key")
+	}
+	var rec idempotencyRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return err
+	}
+	if rec.Hash != hash {
+		return fiber.NewError(http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+	}
+	if rec.Status == 0 {
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return fiber.NewError(http.StatusConflict, "request with this Idempotency-Key is in progress")
+	}
+
+	for h, v := range rec.Headers {
+		c.Set(h, v)
+	}
+	c.Set("Idempotent-Replayed", "true")
+	c.Status(rec.Status)
+	return c.Send(rec.Body)
+}
@@ -5,6 +5,8 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/types"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -20,34 +22,39 @@ func NewLinksHandlers(appScheme string) *LinksHandlers {
 }
 
 type settleReq struct {
-	ToVPA       string `json:"to_vpa"`       // e.g. apurva@okhdfcbank
-	ToName      string `json:"to_name"`      // e.g. Apurva S
-	AmountPaise int64  `json:"amount_paise"` // e.g. 12500 for ₹125.00
-	Note        string `json:"note"`         // e.g. Goa Trip settle-up
+	ToVPA       string      `json:"to_vpa"`       // e.g. apurva@okhdfcbank
+	ToName      string      `json:"to_name"`      // e.g. Apurva S
+	AmountPaise types.Money `json:"amount_paise"` // e.g. 12500 for ₹125.00
+	Note        string      `json:"note"`         // e.g. Goa Trip settle-up
 }
 
 func (h *LinksHandlers) HandleBuildSettleLink(c *fiber.Ctx) error {
 	var req settleReq
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
-	}
-	if req.ToVPA == "" || req.AmountPaise <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to_vpa and amount_paise required"})
+		return errInvalidJSON
 	}
+	var invalid db.ValidationError
+	switch {
+	case req.ToVPA == "":
+		invalid.Add("to_vpa", "required")
 	// super-basic VPA sanity (user@psp). Keep it loose to avoid false negatives.
-	if !strings.Contains(req.ToVPA, "@") {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid vpa"})
+	case !strings.Contains(req.ToVPA, "@"):
+		invalid.Add("to_vpa", "must look like user@psp")
+	}
+	if req.AmountPaise <= 0 {
+		invalid.Add("amount_paise", "must be > 0")
+	}
+	if err := invalid.Err(); err != nil {
+		return err
 	}
-
-	// UPI amount is in rupees with 2 decimals; we store paise
-	rupees := float64(req.AmountPaise) / 100.0
 
 	// Build UPI deep link: upi://pay?pa=<vpa>&pn=<name>&am=<rupees>&cu=INR&tn=<note>
+	// UPI amount is in rupees with 2 decimals; we store paise
 	upi := fmt.Sprintf(
-		"upi://pay?pa=%s&pn=%s&am=%.2f&cu=INR&tn=%s",
+		"upi://pay?pa=%s&pn=%s&am=%s&cu=INR&tn=%s",
 		url.QueryEscape(req.ToVPA),
 		url.QueryEscape(req.ToName),
-		rupees,
+		req.AmountPaise.Rupees(),
 		url.QueryEscape(req.Note),
 	)
 
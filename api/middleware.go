@@ -1,25 +1,219 @@
 package api
 
 import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/akarshgo/paysplit/db"
 	"github.com/akarshgo/paysplit/logger"
+	"github.com/akarshgo/paysplit/metrics"
+	"github.com/akarshgo/paysplit/tracing"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-func RequestIDMiddleware() fiber.Handler {
+// AccessLogMiddleware goes first. It keeps the caller's X-Request-ID if it
+// sent a usable one, so a request can be followed through a gateway, or
+// makes a new one; puts a logger carrying it in the request context for
+// handlers and stores (logger.From); and logs one record per request once
+// the response is decided. log is the base logger; phones, emails and VPAs
+// are masked by it (logger.New).
+func AccessLogMiddleware(log *zap.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		reqID := uuid.New().String()
+		start := time.Now()
+		reqID := c.Get("X-Request-ID")
+		if !validRequestID(reqID) {
+			reqID = uuid.New().String()
+			c.Locals(localsRequestIDGenerated, true)
+		}
 		c.Set("X-Request-ID", reqID)
 		c.Locals("request_id", reqID)
 
-		// log each request
-		logger.Log.Info("incoming request",
-			zap.String("id", reqID),
+		// stores attribute their audit entries from the request context
+		ctx := db.WithAuditInfo(c.UserContext(), db.AuditInfo{ActorID: actorID(c), RequestID: reqID})
+		c.SetUserContext(logger.WithContext(ctx, log.With(zap.String("request_id", reqID))))
+
+		if err := c.Next(); err != nil {
+			if err := c.App().Config().ErrorHandler(c, err); err != nil {
+				return err
+			}
+		}
+
+		status := c.Response().StatusCode()
+		fields := []zap.Field{
 			zap.String("method", c.Method()),
+			zap.String("route", routeLabel(c)),
 			zap.String("path", c.Path()),
+			zap.String("query", logger.RedactQuery(string(c.Request().URI().QueryString()))),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", len(c.Response().Body())),
+			zap.String("ip", c.IP()),
+			zap.String("user_agent", c.Get(fiber.HeaderUserAgent)),
+		}
+		if actor := actorID(c); actor != "" {
+			fields = append(fields, zap.String("actor_id", actor))
+		}
+		// tracing may have added the trace ID since
+		reqLog := logger.From(c.UserContext())
+		if err, ok := c.Locals(localsError).(error); ok {
+			reqLog.Error("request", append(fields, zap.Error(err))...)
+		} else {
+			reqLog.Info("request", fields...)
+		}
+		return nil
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// TracingMiddleware starts the server span for each request, continuing the
+// caller's trace when it sends a W3C traceparent. Otherwise a request ID the
+// server generated becomes the trace ID, so the X-Request-ID finds the trace;
+// a client's own ID is only an attribute, it mustn't pick our trace IDs. Spans
+// from stores and Redis hang off c.UserContext().
+func TracingMiddleware() fiber.Handler {
+	tracer := otel.Tracer("github.com/akarshgo/paysplit/api")
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), requestCarrier{c})
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			generated, _ := c.Locals(localsRequestIDGenerated).(bool)
+			if id, err := uuid.Parse(requestID(c)); generated && err == nil {
+				ctx = tracing.WithTraceID(ctx, trace.TraceID(id))
+			}
+		}
+		ctx, span := tracer.Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+				attribute.String("request_id", requestID(c)),
+			),
 		)
+		defer span.End()
+		if sc := span.SpanContext(); sc.IsValid() {
+			ctx = logger.WithContext(ctx, logger.From(ctx).With(zap.String("trace_id", sc.TraceID().String())))
+		}
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// the route is only known now; "GET /v1/groups/:id" keeps span names bounded
+		if route := routeLabel(c); route != unmatchedRoute {
+			span.SetName(c.Method() + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if err != nil || status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		return err
+	}
+}
+
+// localsRequestIDGenerated is set when AccessLogMiddleware made the request ID
+// rather than taking the client's.
+const localsRequestIDGenerated = "request_id_generated"
+
+func requestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("request_id").(string)
+	return id
+}
+
+// requestCarrier reads propagation headers off the request.
+type requestCarrier struct{ c *fiber.Ctx }
+
+func (r requestCarrier) Get(key string) string { return r.c.Get(key) }
+func (r requestCarrier) Set(string, string)    {}
+func (r requestCarrier) Keys() []string {
+	headers := r.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	return keys
+}
 
+// MetricsMiddleware counts and times every request by route template and
+// status. Errors are rendered here rather than by fiber afterwards, so the
+// status recorded is the one the client gets.
+func MetricsMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		if err := c.Next(); err != nil {
+			if err := c.App().Config().ErrorHandler(c, err); err != nil {
+				return err
+			}
+		}
+
+		metrics.ObserveHTTP(c.Method(), routeLabel(c), c.Response().StatusCode(), time.Since(start).Seconds())
+		return nil
+	}
+}
+
+// BodyLimit refuses request bodies over limit with a 413. The server streams
+// bodies bigger than its own BodyLimit rather than refusing them, so a route
+// that takes more than fiber's default can set its own limit here; the
+// stream is read up to limit and no further, then handed on as the body.
+func BodyLimit(limit int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := c.Request()
+		if !req.IsBodyStream() {
+			// the raw body; c.Body() would inflate a compressed one first
+			if len(req.Body()) > limit {
+				return fiber.ErrRequestEntityTooLarge
+			}
+			return c.Next()
+		}
+
+		// the rest of the body is still on the connection, it can't be reused
+		if req.Header.ContentLength() > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+		if err != nil {
+			c.Context().SetConnectionClose()
+			return fiber.NewError(http.StatusBadRequest, "could not read request body")
+		}
+		if len(body) > limit {
+			c.Context().SetConnectionClose()
+			return fiber.ErrRequestEntityTooLarge
+		}
+		req.SetBody(body)
 		return c.Next()
 	}
 }
+
+const unmatchedRoute = "unmatched"
+
+// routeLabel is the matched route template, e.g. /v1/groups/:id. The
+// middlewares are mounted at /, so that is the route when no handler
+// matched; the raw path mustn't be used instead (unbounded, may hold PII).
+func routeLabel(c *fiber.Ctx) string {
+	if route := c.Route().Path; route != "/" {
+		return route
+	}
+	return unmatchedRoute
+}
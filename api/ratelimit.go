@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/akarshgo/paysplit/config"
+	"github.com/akarshgo/paysplit/logger"
+	"github.com/akarshgo/paysplit/metrics"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Rate limits use a sliding window counter: the count for the current
+// fixed window plus the previous window's count, weighted by how much of it
+// still overlaps the sliding window. Two integers per caller per policy and
+// accurate to within a few percent.
+
+// slidingWindow counts and admits one request atomically. KEYS are the
+// current and previous window; ARGV the limit, window and how far into the
+// current window we are (ms). Returns {allowed, used}.
+var slidingWindow = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+local used = math.floor(prev * (window - elapsed) / window) + cur
+if used >= limit then
+  return {0, used}
+end
+if redis.call('INCR', KEYS[1]) == 1 then
+  redis.call('PEXPIRE', KEYS[1], window * 2)
+end
+return {1, used + 1}
+`)
+
+const redisRetryDelay = 5 * time.Second
+
+var errRedisSkipped = errors.New("redis skipped")
+
+type ratePolicy struct {
+	config.RateLimit
+	method   string
+	segments []string // nil for "*"
+}
+
+func (p *ratePolicy) matches(method string, segments []string) bool {
+	if p.method != method || len(p.segments) != len(segments) {
+		return false
+	}
+	for i, s := range p.segments {
+		if !strings.HasPrefix(s, ":") && s != segments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RateLimitMiddleware enforces the policies on /v1 routes. Every response
+// carries RateLimit-Limit/-Remaining/-Reset and RateLimit-Policy; one over
+// the limit is a 429 with Retry-After. While Redis is unreachable each
+// instance counts on its own in memory, so the effective limit is per
+// instance until it is back.
+//
+// A "user" policy counts the caller's IP as well and both have to pass:
+// X-User-ID isn't verified, so on its own a client could reset its count
+// by sending a new one.
+func RateLimitMiddleware(rdb *redis.Client, limits []config.RateLimit) fiber.Handler {
+	var routes []*ratePolicy
+	var fallback *ratePolicy
+	for _, rl := range limits {
+		p := &ratePolicy{RateLimit: rl}
+		if rl.Route == "*" {
+			fallback = p
+			continue
+		}
+		method, path, _ := strings.Cut(rl.Route, " ")
+		p.method, p.segments = method, strings.Split(strings.Trim(path, "/"), "/")
+		routes = append(routes, p)
+	}
+	mem := newMemoryWindows()
+	var degraded atomic.Bool
+	var retryRedisAt atomic.Int64 // unix nanos
+
+	return func(c *fiber.Ctx) error {
+		segments := strings.Split(strings.Trim(c.Path(), "/"), "/")
+		policy := fallback
+		for _, p := range routes {
+			if p.matches(c.Method(), segments) {
+				policy = p
+				break
+			}
+		}
+		if policy == nil {
+			return c.Next()
+		}
+
+		who := []string{"ip:" + c.IP()}
+		if policy.By == "user" {
+			if actor := actorID(c); actor != "" {
+				who = append(who, "user:"+actor)
+			}
+		}
+		now := time.Now()
+		windowMs := policy.Window.Milliseconds()
+		idx, elapsed := now.UnixMilli()/windowMs, now.UnixMilli()%windowMs
+
+		count := func(who string) (bool, int64) {
+			// the hash tag keeps both windows on one cluster slot
+			key := "paysplit:rl:{" + policy.Route + "|" + who + "}:"
+			var allowed bool
+			var used int64
+			err := errRedisSkipped
+			if rdb != nil && now.UnixNano() >= retryRedisAt.Load() {
+				allowed, used, err = countRedis(c.UserContext(), rdb, key, idx, elapsed, policy)
+			}
+			switch {
+			case err != nil:
+				if err != errRedisSkipped {
+					// don't make every request wait on a dead Redis
+					retryRedisAt.Store(now.Add(redisRetryDelay).UnixNano())
+					if !degraded.Swap(true) {
+						logger.From(c.UserContext()).Warn("rate limit: redis unavailable, counting in memory", zap.Error(err))
+					}
+				}
+				allowed, used = mem.count(key, idx, elapsed, policy, now)
+			case degraded.Swap(false):
+				logger.From(c.UserContext()).Info("rate limit: redis is back")
+			}
+			return allowed, used
+		}
+
+		// the first count that refuses decides; the headers show the tighter one
+		allowed, used := true, int64(0)
+		for _, w := range who {
+			ok, n := count(w)
+			used = max(used, n)
+			if !ok {
+				allowed = false
+				break
+			}
+		}
+
+		reset := int64(math.Ceil(float64(windowMs-elapsed) / 1000))
+		c.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Set("RateLimit-Remaining", strconv.FormatInt(max(int64(policy.Limit)-used, 0), 10))
+		c.Set("RateLimit-Reset", strconv.FormatInt(reset, 10))
+		c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int64(policy.Window.Seconds())))
+		if !allowed {
+			metrics.RateLimited.WithLabelValues(policy.Route).Inc()
+			c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(reset, 10))
+			return &Problem{Status: http.StatusTooManyRequests, Code: "rate_limited",
+				Detail: fmt.Sprintf("rate limit of %d requests per %s exceeded, retry in %ds", policy.Limit, policy.Window, reset)}
+		}
+		return c.Next()
+	}
+}
+
+func countRedis(ctx context.Context, rdb *redis.Client, key string, idx, elapsed int64, p *ratePolicy) (bool, int64, error) {
+	keys := []string{key + strconv.FormatInt(idx, 10), key + strconv.FormatInt(idx-1, 10)}
+	res, err := slidingWindow.Run(ctx, rdb, keys, p.Limit, p.Window.Milliseconds(), elapsed).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	return res[0] == 1, res[1], nil
+}
+
+// memoryWindows is the same counter in process memory.
+type memoryWindows struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+type memoryWindow struct {
+	idx       int64
+	cur, prev int64
+	window    time.Duration
+}
+
+func newMemoryWindows() *memoryWindows {
+	return &memoryWindows{windows: map[string]*memoryWindow{}, lastSweep: time.Now()}
+}
+
+func (m *memoryWindows) count(key string, idx, elapsed int64, p *ratePolicy, now time.Time) (bool, int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// drop callers whose windows have both passed, at most once a minute
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, w := range m.windows {
+			if now.UnixMilli()/w.window.Milliseconds() > w.idx+1 {
+				delete(m.windows, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	w := m.windows[key]
+	if w == nil {
+		w = &memoryWindow{idx: idx, window: p.Window}
+		m.windows[key] = w
+	}
+	switch {
+	case w.idx == idx-1:
+		w.idx, w.prev, w.cur = idx, w.cur, 0
+	case w.idx < idx-1:
+		w.idx, w.prev, w.cur = idx, 0, 0
+	}
+
+	windowMs := p.Window.Milliseconds()
+	used := w.prev*(windowMs-elapsed)/windowMs + w.cur
+	if used >= int64(p.Limit) {
+		return false, used
+	}
+	w.cur++
+	return true, used + 1
+}
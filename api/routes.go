@@ -1,16 +1,30 @@
 package api
 
 import (
-	"context"
-
-	rediscli "github.com/akarshgo/paysplit/redis"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func SetupRoutes(app *fiber.App, userHandlers *UserHandlers, groupHandlers *GroupHandlers, expenseHandlers *ExpenseHandlers, linksHandlers *LinksHandlers) {
+func SetupRoutes(app *fiber.App, userHandlers *UserHandlers, groupHandlers *GroupHandlers, expenseHandlers *ExpenseHandlers, linksHandlers *LinksHandlers, settlementHandlers *SettlementHandlers, statementHandlers *StatementHandlers, draftHandlers *DraftHandlers, attachmentHandlers *AttachmentHandlers, activityHandlers *ActivityHandlers, adminHandlers *AdminHandlers, healthHandlers *HealthHandlers, mw ...fiber.Handler) {
 	// v1 prefix
 	v1 := app.Group("/v1")
 
+	// Routes taking bigger bodies than fiber's default carry their own limit
+	// and go first: handlers run in registration order and a route ends the
+	// chain, so the default limit below never runs for them. mw (idempotency)
+	// reads the body, so it always runs after a limit.
+	limited := func(limit int, h fiber.Handler) []fiber.Handler {
+		return append(append([]fiber.Handler{BodyLimit(limit)}, mw...), h)
+	}
+	v1.Post("/groups/:id/expenses/:eid/attachments", limited(maxUploadBody, attachmentHandlers.HandleUploadAttachment)...)
+	v1.Post("/users/:id/statements/import", limited(maxStatementBody, statementHandlers.HandleImportStatement)...)
+
+	app.Use(BodyLimit(fiber.DefaultBodyLimit))
+	for _, h := range mw {
+		app.Use(h)
+	}
+
 	// Users
 	v1.Post("/users", userHandlers.HandleCreateUser)
 	v1.Get("/users", userHandlers.HandleGetUsers)
@@ -22,31 +36,58 @@ func SetupRoutes(app *fiber.App, userHandlers *UserHandlers, groupHandlers *Grou
 	v1.Post("/groups", groupHandlers.HandleCreateGroup)
 	v1.Post("/groups/:id/members", groupHandlers.HandleAddMember)
 	v1.Get("/groups", groupHandlers.HandleListGroups)
+	v1.Get("/groups/:id", groupHandlers.HandleGetGroup)
+	v1.Patch("/groups/:id", groupHandlers.HandleUpdateGroup)
+	v1.Post("/groups/:id/categories", groupHandlers.HandleAddCategory)
+	v1.Get("/groups/:id/categories", groupHandlers.HandleListCategories)
 
 	//Expenses
 	v1.Post("/groups/:id/expenses", expenseHandlers.HandleCreateExpense)
 	v1.Get("/groups/:id/expenses", expenseHandlers.HandleListExpenses)
+	v1.Get("/groups/:id/expenses/:eid", expenseHandlers.HandleGetExpense)
+	v1.Patch("/groups/:id/expenses/:eid", expenseHandlers.HandleUpdateExpense)
+	v1.Delete("/groups/:id/expenses/:eid", expenseHandlers.HandleDeleteExpense)
+
+	//Receipts / attachments; the upload route is above
+	v1.Get("/groups/:id/expenses/:eid/attachments", attachmentHandlers.HandleListAttachments)
+	v1.Get("/groups/:id/expenses/:eid/attachments/:aid", attachmentHandlers.HandleDownloadAttachment)
+	v1.Delete("/groups/:id/expenses/:eid/attachments/:aid", attachmentHandlers.HandleDeleteAttachment)
 
 	v1.Get("/groups/:id/balances", expenseHandlers.HandleGroupBalances)
 	v1.Get("/groups/:id/simplify", expenseHandlers.HandleSimplifyDebts)
+	v1.Get("/groups/:id/reports/categories", expenseHandlers.HandleCategoryReport)
+
+	//Settlements
+	v1.Post("/groups/:id/settlements", settlementHandlers.HandleCreateSettlement)
+	v1.Get("/groups/:id/settlements", settlementHandlers.HandleListSettlements)
+	v1.Post("/settlements/bulk", settlementHandlers.HandleBulkCreateSettlements)
+
+	//Comments & activity feed
+	v1.Get("/groups/:id/activity", activityHandlers.HandleGroupFeed)
+	v1.Post("/groups/:id/expenses/:eid/comments", activityHandlers.HandleCreateExpenseComment)
+	v1.Get("/groups/:id/expenses/:eid/comments", activityHandlers.HandleListExpenseComments)
+	v1.Post("/groups/:id/settlements/:sid/comments", activityHandlers.HandleCreateSettlementComment)
+	v1.Get("/groups/:id/settlements/:sid/comments", activityHandlers.HandleListSettlementComments)
+
+	//Drafts from forwarded bank/UPI SMS
+	v1.Post("/groups/:id/drafts/sms", draftHandlers.HandleDraftFromSMS)
+
+	//Admin
+	admin := v1.Group("/admin", adminHandlers.RequireAdmin)
+	admin.Get("/audit", adminHandlers.HandleListAudit)
+	admin.Get("/ledger/verify", adminHandlers.HandleVerifyLedger)
+	admin.Get("/ledger/journals", adminHandlers.HandleListJournals)
 
 	//UPI Links
 	v1.Post("/links/settle", linksHandlers.HandleBuildSettleLink)
 
-	//Helath Check
-	v1.Get("/health", HandleHealth)
+	//Health Checks
+	app.Get("/livez", healthHandlers.HandleLivez)
+	app.Get("/readyz", healthHandlers.HandleReadyz)
+	v1.Get("/health", healthHandlers.HandleReadyz)
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.Handler()))
 
 	//Redis
-	v1.Get("/ping-redis", func(c *fiber.Ctx) error {
-		ctx := context.Background()
-		if err := rediscli.Rdb.Set(ctx, "paysplit:ping", "pong", 0).Err(); err != nil {
-			return c.Status(500).JSON(fiber.Map{"redis": "error", "err": err.Error()})
-		}
-		val, err := rediscli.Rdb.Get(ctx, "paysplit:ping").Result()
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"redis": "error", "err": err.Error()})
-		}
-		return c.JSON(fiber.Map{"redis": "ok", "val": val})
-	})
+	v1.Get("/ping-redis", healthHandlers.HandlePingRedis)
 
 }
@@ -0,0 +1,581 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akarshgo/paysplit/blob"
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// fixture is the API over memory stores with a small group in it: asha
+// paid ₹10 for dinner split with bala, bala paid asha back ₹2, and the
+// dinner has a PDF receipt. chitra is a member with no expenses yet.
+type fixture struct {
+	app *fiber.App
+	ids map[string]string // placeholder -> ID, e.g. "{group}"
+}
+
+const adminToken = "s3cret"
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	m := db.NewMemoryDB()
+	users, groups, expenses := db.NewMemoryUserStore(m), db.NewMemoryGroupStore(m), db.NewMemoryExpenseStore(m)
+	settlements, attachments := newFakeSettlementStore(), newFakeAttachmentStore()
+	activity := &fakeActivityStore{}
+	blobs, err := blob.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]string{"{missing}": uuid.New().String()}
+	email, vpa := "asha@example.com", "bala@okaxis"
+	for _, u := range []*types.User{{Name: "asha", Email: &email}, {Name: "bala", UPI: &vpa}, {Name: "chitra"}, {Name: "ravi"}} {
+		if err := users.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		ids["{"+u.Name+"}"] = u.ID
+	}
+	asha, bala := ids["{asha}"], ids["{bala}"]
+	g, err := groups.Create(ctx, &types.Group{Name: "trip", CreatedBy: asha})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ravi isn't in the group
+	for _, u := range []string{bala, ids["{chitra}"]} {
+		if err := groups.AddMember(ctx, g, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := &types.Expense{GroupID: g, PaidBy: asha, AmountPaise: 1000, Currency: "INR", Note: "dinner",
+		Category: types.CategoryFood, SplitKind: types.SplitEqual}
+	if _, err := expenses.Create(ctx, e, []types.ExpenseSplit{{UserID: asha, Exact: 500}, {UserID: bala, Exact: 500}}); err != nil {
+		t.Fatal(err)
+	}
+	s, _ := settlements.Create(ctx, &types.Settlement{GroupID: g, FromUser: bala, ToUser: asha, Amount: 200, Method: "upi"})
+	receipt := []byte("%PDF-1.4 receipt")
+	a := &types.Attachment{ID: uuid.New().String(), ExpenseID: e.ID, FileName: "bill.pdf", ContentType: "application/pdf",
+		SizeBytes: int64(len(receipt)), StorageKey: "expenses/" + e.ID + "/bill"}
+	if err := blobs.Put(ctx, a.StorageKey, bytes.NewReader(receipt), a.SizeBytes, a.ContentType); err != nil {
+		t.Fatal(err)
+	}
+	_ = attachments.Create(ctx, a)
+	ids["{group}"], ids["{expense}"], ids["{settlement}"], ids["{attachment}"] = g, e.ID, s, a.ID
+
+	// nothing listens on port 1, so readiness reports both down
+	sqlDB, err := sql.Open("postgres", "postgres://paysplit@127.0.0.1:1/paysplit?sslmode=disable&connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	t.Cleanup(func() {
+		sqlDB.Close()
+		rdb.Close()
+	})
+
+	feed := NewActivityRecorder(activity)
+	// as in cmd/api, so big bodies reach the route limits
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true, ErrorHandler: ErrorHandler})
+	SetupRoutes(app,
+		NewUserHandlers(users),
+		NewGroupHanlders(groups, feed),
+		NewExpenseHandlers(expenses, groups, attachments, blobs, feed),
+		NewLinksHandlers("paysplit"),
+		NewSettlementHandlers(settlements, groups, feed),
+		NewStatementHandlers(users, groups, expenses, settlements),
+		NewDraftHandlers(users, groups),
+		NewAttachmentHandlers(expenses, attachments, blobs, feed),
+		NewActivityHandlers(activity, &fakeCommentStore{}, expenses, settlements, feed),
+		NewAdminHandlers(fakeAuditStore{}, fakeLedgerStore{}, adminToken),
+		NewHealthHandlers(sqlDB, rdb),
+	)
+	return &fixture{app: app, ids: ids}
+}
+
+var placeholder = regexp.MustCompile(`\{[a-z]+\}`)
+
+func (f *fixture) expand(s string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(p string) string { return f.ids[p] })
+}
+
+type routeCase struct {
+	name   string
+	method string
+	path   string // {asha}, {group}, ... are replaced with fixture IDs, in body too
+	body   string
+	// defaults to JSON when there is a body
+	contentType string
+	header      map[string]string
+	status      int
+	code        string   // problem code, for errors
+	fields      []string // for 422 validation_failed
+	check       bodyCheck
+}
+
+func TestRoutes(t *testing.T) {
+	statementCSV, statementType := multipartFile("statement.csv", []byte(
+		"Date,Narration,Withdrawal Amt,Deposit Amt,UPI Ref No\n"+
+			"01/01/2025,UPI-BALA-bala@okaxis-412345678901,,300.00,412345678901\n"))
+	bigCSV, bigCSVType := multipartFile("statement.csv", make([]byte, maxStatementBytes+1))
+	pngFile, pngType := multipartFile("receipt.png", tinyPNG(t))
+	bigPNG, bigType := multipartFile("receipt.png", append(tinyPNG(t), make([]byte, fiber.DefaultBodyLimit)...))
+	hugePNG, hugeType := multipartFile("receipt.png", make([]byte, maxUploadBody))
+	textFile, textType := multipartFile("notes.txt", []byte("not a receipt"))
+	noFile, noFileType := multipartFile("", nil)
+	admin := map[string]string{"X-Admin-Token": adminToken}
+	ifMatch := func(tag string) map[string]string { return map[string]string{"If-Match": tag} }
+	sms := "Rs.450.00 debited from A/c XX1234 to VPA %s on 12-01-24. UPI Ref 412345678901"
+
+	tests := []routeCase{
+		// users
+		{name: "create user", method: "POST", path: "/v1/users", body: `{"name":" dev "}`, status: 201,
+			check: all(hasKeys("id", "name", "version", "created_at"), field("name", "dev"), etag(`"1"`))},
+		{name: "create user without name", method: "POST", path: "/v1/users", body: `{}`, status: 422, fields: []string{"name"}},
+		{name: "create user with taken email", method: "POST", path: "/v1/users", body: `{"name":"x","email":"asha@example.com"}`, status: 409, code: "already_exists"},
+		{name: "create user over the body limit", method: "POST", path: "/v1/users", body: `{"name":"` + strings.Repeat("a", fiber.DefaultBodyLimit) + `"}`, status: 413, code: "payload_too_large"},
+		{name: "create user, not JSON", method: "POST", path: "/v1/users", body: `{`, status: 400, code: "bad_request"},
+		{name: "list users", method: "GET", path: "/v1/users", status: 200, check: arrayLen(4)},
+		{name: "search users", method: "GET", path: "/v1/users?q=ASH", status: 200, check: all(arrayLen(1), firstField("name", "asha"))},
+		{name: "get user", method: "GET", path: "/v1/users/{asha}", status: 200, check: all(field("id", "{asha}"), field("email", "asha@example.com"), etag(`"1"`))},
+		{name: "get missing user", method: "GET", path: "/v1/users/{missing}", status: 404, code: "not_found"},
+		{name: "update user", method: "PATCH", path: "/v1/users/{asha}", body: `{"name":"asha k"}`, header: ifMatch(`"1"`), status: 200,
+			check: all(field("version", 2.0), etag(`"2"`))},
+		{name: "update user without If-Match", method: "PATCH", path: "/v1/users/{asha}", body: `{"name":"asha k"}`, status: 428, code: "precondition_required"},
+		{name: "update user with stale If-Match", method: "PATCH", path: "/v1/users/{asha}", body: `{"name":"asha k"}`, header: ifMatch(`"7"`), status: 412, code: "version_conflict"},
+		{name: "update user without name", method: "PATCH", path: "/v1/users/{asha}", body: `{"name":" "}`, header: ifMatch("*"), status: 422, fields: []string{"name"}},
+		{name: "delete user", method: "DELETE", path: "/v1/users/{chitra}", header: ifMatch(`"1"`), status: 204},
+		{name: "delete user still in use", method: "DELETE", path: "/v1/users/{asha}", header: ifMatch("*"), status: 409, code: "in_use"},
+
+		// groups
+		{name: "create group", method: "POST", path: "/v1/groups", body: `{"name":"goa","created_by":"{bala}"}`, status: 201, check: hasKeys("id")},
+		{name: "create group without fields", method: "POST", path: "/v1/groups", body: `{}`, status: 422, fields: []string{"name", "created_by"}},
+		{name: "create group by missing user", method: "POST", path: "/v1/groups", body: `{"name":"goa","created_by":"{missing}"}`, status: 422, fields: []string{"created_by"}},
+		{name: "list groups", method: "GET", path: "/v1/groups", status: 200},
+		{name: "get group", method: "GET", path: "/v1/groups/{group}", status: 200,
+			check: all(field("name", "trip"), field("created_by", "{asha}"), etag(`"1"`))},
+		{name: "get missing group", method: "GET", path: "/v1/groups/{missing}", status: 404, code: "not_found"},
+		{name: "rename group", method: "PATCH", path: "/v1/groups/{group}", body: `{"name":"goa trip"}`, header: ifMatch(`"1"`), status: 200,
+			check: all(field("name", "goa trip"), field("version", 2.0))},
+		{name: "rename group without If-Match", method: "PATCH", path: "/v1/groups/{group}", body: `{"name":"goa trip"}`, status: 428, code: "precondition_required"},
+		{name: "rename group to nothing", method: "PATCH", path: "/v1/groups/{group}", body: `{"name":""}`, header: ifMatch("*"), status: 422, fields: []string{"name"}},
+		{name: "add member again", method: "POST", path: "/v1/groups/{group}/members", body: `{"user_id":"{bala}"}`, status: 204},
+		{name: "add member without user", method: "POST", path: "/v1/groups/{group}/members", body: `{}`, status: 422, fields: []string{"user_id"}},
+		{name: "add missing user as member", method: "POST", path: "/v1/groups/{group}/members", body: `{"user_id":"{missing}"}`, status: 422, fields: []string{"user_id"}},
+		{name: "add category", method: "POST", path: "/v1/groups/{group}/categories", body: `{"name":" Fuel ","keywords":["Petrol",""]}`, status: 201,
+			check: all(field("name", "fuel"), field("keywords", []any{"petrol"}))},
+		{name: "add built-in category", method: "POST", path: "/v1/groups/{group}/categories", body: `{"name":"food"}`, status: 422, fields: []string{"name"}},
+		{name: "list categories", method: "GET", path: "/v1/groups/{group}/categories", status: 200, check: all(hasKeys("builtin", "custom"), field("custom", []any{}))},
+
+		// expenses
+		{name: "create expense", method: "POST", path: "/v1/groups/{group}/expenses", status: 201, check: all(hasKeys("id"), etag(`"1"`)),
+			body: `{"paid_by":"{bala}","amount_paise":900,"note":"cab","split":{"kind":"equal","users":[{"user_id":"{asha}"},{"user_id":"{bala}"},{"user_id":"{chitra}"}]}}`},
+		{name: "create expense without payer", method: "POST", path: "/v1/groups/{group}/expenses", body: `{"amount_paise":100}`, status: 422, fields: []string{"paid_by"}},
+		{name: "create expense without amount or users", method: "POST", path: "/v1/groups/{group}/expenses", body: `{"paid_by":"{asha}","split":{"kind":"equal"}}`,
+			status: 422, fields: []string{"amount_paise", "split.users"}},
+		{name: "create expense with a share missing", method: "POST", path: "/v1/groups/{group}/expenses", status: 422, fields: []string{"split.users[1].shares"},
+			body: `{"paid_by":"{asha}","amount_paise":100,"split":{"kind":"shares","users":[{"user_id":"{asha}","shares":1},{"user_id":"{bala}"}]}}`},
+		{name: "create expense with unknown split kind", method: "POST", path: "/v1/groups/{group}/expenses", status: 422, fields: []string{"split.kind"},
+			body: `{"paid_by":"{asha}","amount_paise":100,"split":{"kind":"halves","users":[{"user_id":"{asha}"}]}}`},
+		{name: "create expense with unknown category", method: "POST", path: "/v1/groups/{group}/expenses", status: 422, fields: []string{"category"},
+			body: `{"paid_by":"{asha}","amount_paise":100,"category":"yachts","split":{"kind":"equal","users":[{"user_id":"{asha}"}]}}`},
+		{name: "create expense paid by missing user", method: "POST", path: "/v1/groups/{group}/expenses", status: 422, fields: []string{"paid_by"},
+			body: `{"paid_by":"{missing}","amount_paise":100,"split":{"kind":"equal","users":[{"user_id":"{asha}"}]}}`},
+		{name: "create expense with people outside the group", method: "POST", path: "/v1/groups/{group}/expenses", status: 422, fields: []string{"paid_by", "split.users[1].user_id"},
+			body: `{"paid_by":"{ravi}","amount_paise":100,"split":{"kind":"equal","users":[{"user_id":"{asha}"},{"user_id":"{ravi}"}]}}`},
+		{name: "create expense, not JSON", method: "POST", path: "/v1/groups/{group}/expenses", body: `[`, status: 400, code: "bad_request"},
+		{name: "create duplicate expense", method: "POST", path: "/v1/groups/{group}/expenses", status: 409, code: "possible_duplicate",
+			body:  `{"paid_by":"{asha}","amount_paise":1000,"note":"Dinner","split":{"kind":"equal","users":[{"user_id":"{asha}"},{"user_id":"{bala}"}]}}`,
+			check: all(hasKeys("candidates", "hint"), firstCandidate("{expense}"))},
+		{name: "merge duplicate expense", method: "POST", path: "/v1/groups/{group}/expenses", header: ifMatch(`"1"`), status: 200,
+			body:  `{"paid_by":"{asha}","amount_paise":1000,"note":"thalassa","merge_into":"{expense}","split":{"kind":"equal","users":[{"user_id":"{asha}"},{"user_id":"{chitra}"}]}}`,
+			check: all(field("id", "{expense}"), field("note", "dinner; thalassa"), etag(`"2"`))},
+		{name: "merge duplicate expense without If-Match", method: "POST", path: "/v1/groups/{group}/expenses", status: 428, code: "precondition_required",
+			body: `{"paid_by":"{asha}","amount_paise":1000,"merge_into":"{expense}","split":{"kind":"equal","users":[{"user_id":"{chitra}"}]}}`},
+		{name: "merge duplicate expense with stale If-Match", method: "POST", path: "/v1/groups/{group}/expenses", header: ifMatch(`"7"`), status: 412, code: "version_conflict",
+			body: `{"paid_by":"{asha}","amount_paise":1000,"merge_into":"{expense}","split":{"kind":"equal","users":[{"user_id":"{chitra}"}]}}`},
+		{name: "list expenses", method: "GET", path: "/v1/groups/{group}/expenses", status: 200, check: all(arrayLen(1), firstField("id", "{expense}"))},
+		{name: "list expenses with splits", method: "GET", path: "/v1/groups/{group}/expenses?embed=splits&paid_by={asha}", status: 200,
+			check: all(arrayLen(1), firstHasKeys("splits", "paid_by_name"))},
+		{name: "list expenses filtered out", method: "GET", path: "/v1/groups/{group}/expenses?category=travel", status: 200, check: arrayLen(0)},
+		{name: "list expenses with bad date", method: "GET", path: "/v1/groups/{group}/expenses?from=yesterday", status: 422, fields: []string{"from"}},
+		{name: "list expenses with a non-UUID payer", method: "GET", path: "/v1/groups/{group}/expenses?paid_by=asha", status: 422, fields: []string{"paid_by"}},
+		{name: "list expenses with a cursor past a non-UUID id", method: "GET", path: "/v1/groups/{group}/expenses?cursor=MjAyNS0wMS0wMVQwMDowMDowMFp8MSBPUiAxPTE", status: 422, fields: []string{"cursor"}},
+		{name: "list expenses with bad cursor", method: "GET", path: "/v1/groups/{group}/expenses?cursor=nope", status: 422, fields: []string{"cursor"}},
+		{name: "get expense", method: "GET", path: "/v1/groups/{group}/expenses/{expense}", status: 200,
+			check: all(field("amount_paise", 1000.0), field("paid_by_name", "asha"), hasKeys("splits"), etag(`"1"`))},
+		{name: "get expense from another group", method: "GET", path: "/v1/groups/{missing}/expenses/{expense}", status: 404, code: "not_found"},
+		{name: "update expense amount", method: "PATCH", path: "/v1/groups/{group}/expenses/{expense}", body: `{"amount_paise":1200}`, header: ifMatch(`"1"`), status: 200,
+			check: all(field("amount_paise", 1200.0), field("version", 2.0))},
+		{name: "update expense to no payer", method: "PATCH", path: "/v1/groups/{group}/expenses/{expense}", body: `{"paid_by":""}`, header: ifMatch("*"), status: 422, fields: []string{"paid_by"}},
+		{name: "update expense to a payer outside the group", method: "PATCH", path: "/v1/groups/{group}/expenses/{expense}", body: `{"paid_by":"{ravi}"}`, header: ifMatch("*"), status: 422, fields: []string{"paid_by"}},
+		{name: "update expense without If-Match", method: "PATCH", path: "/v1/groups/{group}/expenses/{expense}", body: `{"note":"x"}`, status: 428, code: "precondition_required"},
+		{name: "delete expense", method: "DELETE", path: "/v1/groups/{group}/expenses/{expense}", header: ifMatch(`"1"`), status: 204},
+		{name: "delete expense with stale If-Match", method: "DELETE", path: "/v1/groups/{group}/expenses/{expense}", header: ifMatch(`"3"`), status: 412, code: "version_conflict"},
+
+		// attachments
+		{name: "upload receipt", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/attachments", body: pngFile, contentType: pngType, status: 201,
+			check: all(field("content_type", "image/png"), field("file_name", "receipt.png"), field("has_thumbnail", true))},
+		{name: "upload over the default body limit", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/attachments", body: bigPNG, contentType: bigType, status: 201},
+		{name: "upload over the upload body limit", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/attachments", body: hugePNG, contentType: hugeType, status: 413, code: "payload_too_large"},
+		{name: "upload without file", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/attachments", body: noFile, contentType: noFileType, status: 422, fields: []string{"file"}},
+		{name: "upload text file", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/attachments", body: textFile, contentType: textType, status: 415, code: "unsupported_media_type"},
+		{name: "upload to missing expense", method: "POST", path: "/v1/groups/{group}/expenses/{missing}/attachments", body: pngFile, contentType: pngType, status: 404, code: "not_found"},
+		{name: "list attachments", method: "GET", path: "/v1/groups/{group}/expenses/{expense}/attachments", status: 200, check: all(arrayLen(1), firstField("file_name", "bill.pdf"))},
+		{name: "download attachment", method: "GET", path: "/v1/groups/{group}/expenses/{expense}/attachments/{attachment}", status: 200,
+			check: rawBody("%PDF-1.4 receipt")},
+		{name: "download missing thumbnail", method: "GET", path: "/v1/groups/{group}/expenses/{expense}/attachments/{attachment}?thumbnail=1", status: 404, code: "not_found"},
+		{name: "download missing attachment", method: "GET", path: "/v1/groups/{group}/expenses/{expense}/attachments/{missing}", status: 404, code: "not_found"},
+		{name: "delete attachment", method: "DELETE", path: "/v1/groups/{group}/expenses/{expense}/attachments/{attachment}", status: 204},
+
+		// balances and reports
+		{name: "balances", method: "GET", path: "/v1/groups/{group}/balances", status: 200, check: all(field("{asha}", 500.0), field("{bala}", -500.0))},
+		{name: "simplify", method: "GET", path: "/v1/groups/{group}/simplify", status: 200,
+			check: all(arrayLen(1), firstField("from", "{bala}"), firstField("to", "{asha}"), firstField("amount_paise", 500.0))},
+		{name: "category report", method: "GET", path: "/v1/groups/{group}/reports/categories", status: 200,
+			check: all(arrayLen(1), firstField("category", "food"), firstField("total_paise", 1000.0))},
+		{name: "category report with bad amount", method: "GET", path: "/v1/groups/{group}/reports/categories?min_amount=-5", status: 422, fields: []string{"min_amount"}},
+
+		// settlements
+		{name: "create settlement", method: "POST", path: "/v1/groups/{group}/settlements", body: `{"from_user":"{bala}","to_user":"{asha}","amount_paise":300}`, status: 201, check: hasKeys("id")},
+		{name: "create empty settlement", method: "POST", path: "/v1/groups/{group}/settlements", body: `{}`, status: 422, fields: []string{"from_user", "to_user", "amount_paise"}},
+		{name: "settle with yourself", method: "POST", path: "/v1/groups/{group}/settlements", body: `{"from_user":"{asha}","to_user":"{asha}","amount_paise":300}`, status: 422, fields: []string{"to_user"}},
+		{name: "settle with someone outside the group", method: "POST", path: "/v1/groups/{group}/settlements", body: `{"from_user":"{ravi}","to_user":"{asha}","amount_paise":300}`, status: 422, fields: []string{"from_user"}},
+		{name: "list settlements", method: "GET", path: "/v1/groups/{group}/settlements", status: 200, check: all(arrayLen(1), firstField("method", "upi"), firstField("amount", 200.0))},
+		{name: "bulk settlements", method: "POST", path: "/v1/settlements/bulk", status: 201, check: field("ids", 2),
+			body: `{"settlements":[{"group_id":"{group}","from_user":"{bala}","to_user":"{asha}","amount_paise":100},{"group_id":"{group}","from_user":"{chitra}","to_user":"{asha}","amount_paise":50,"method":"cash"}]}`},
+		{name: "bulk settlements with bad rows", method: "POST", path: "/v1/settlements/bulk", status: 422, fields: []string{"settlements[1].group_id", "settlements[1].amount_paise"},
+			body: `{"settlements":[{"group_id":"{group}","from_user":"{bala}","to_user":"{asha}","amount_paise":100},{"from_user":"{chitra}","to_user":"{asha}"}]}`},
+		{name: "bulk settlements with someone outside the group", method: "POST", path: "/v1/settlements/bulk", status: 422, fields: []string{"settlements[1].to_user"},
+			body: `{"settlements":[{"group_id":"{group}","from_user":"{bala}","to_user":"{asha}","amount_paise":100},{"group_id":"{group}","from_user":"{bala}","to_user":"{ravi}","amount_paise":50}]}`},
+		{name: "bulk settlements without any", method: "POST", path: "/v1/settlements/bulk", body: `{"settlements":[]}`, status: 422, fields: []string{"settlements"}},
+
+		// activity and comments
+		{name: "activity feed", method: "GET", path: "/v1/groups/{group}/activity", status: 200, check: arrayLen(0)},
+		{name: "activity feed since bad time", method: "GET", path: "/v1/groups/{group}/activity?since=today", status: 422, fields: []string{"since"}},
+		{name: "activity feed with bad cursor", method: "GET", path: "/v1/groups/{group}/activity?cursor=nope", status: 422, fields: []string{"cursor"}},
+		{name: "comment on expense", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/comments", body: `{"body":" was it 10? "}`,
+			header: map[string]string{"X-User-ID": "{bala}"}, status: 201, check: all(field("author_id", "{bala}"), field("body", "was it 10?"), field("target_type", "expense"))},
+		{name: "empty comment", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/comments", body: `{}`, status: 422, fields: []string{"author_id", "body"}},
+		{name: "comment too long", method: "POST", path: "/v1/groups/{group}/expenses/{expense}/comments", status: 422, fields: []string{"body"},
+			body: `{"author_id":"{asha}","body":"` + strings.Repeat("a", maxCommentLen+1) + `"}`},
+		{name: "comment on missing expense", method: "POST", path: "/v1/groups/{group}/expenses/{missing}/comments", body: `{"author_id":"{asha}","body":"hi"}`, status: 404, code: "not_found"},
+		{name: "list expense comments", method: "GET", path: "/v1/groups/{group}/expenses/{expense}/comments", status: 200, check: arrayLen(0)},
+		{name: "comment on settlement", method: "POST", path: "/v1/groups/{group}/settlements/{settlement}/comments", body: `{"author_id":"{asha}","body":"got it"}`, status: 201,
+			check: field("target_id", "{settlement}")},
+		{name: "list settlement comments", method: "GET", path: "/v1/groups/{group}/settlements/{settlement}/comments", status: 200, check: arrayLen(0)},
+		{name: "list comments of missing settlement", method: "GET", path: "/v1/groups/{group}/settlements/{missing}/comments", status: 404, code: "not_found"},
+
+		// statement import
+		{name: "import statement", method: "POST", path: "/v1/users/{asha}/statements/import", body: statementCSV, contentType: statementType, status: 200,
+			check: all(field("transactions", 1.0), hasKeys("suggestions"))},
+		{name: "import without file", method: "POST", path: "/v1/users/{asha}/statements/import", body: noFile, contentType: noFileType, status: 422, fields: []string{"file"}},
+		{name: "import unknown format", method: "POST", path: "/v1/users/{asha}/statements/import?format=xls", body: statementCSV, contentType: statementType, status: 422, fields: []string{"file"}},
+		{name: "import statement over the size limit", method: "POST", path: "/v1/users/{asha}/statements/import", body: bigCSV, contentType: bigCSVType, status: 413, code: "payload_too_large",
+			check: field("detail", "statement too large")},
+		{name: "import for missing user", method: "POST", path: "/v1/users/{missing}/statements/import", body: statementCSV, contentType: statementType, status: 404, code: "not_found"},
+
+		// drafts
+		{name: "draft expense from SMS", method: "POST", path: "/v1/groups/{group}/drafts/sms", status: 200,
+			body:  `{"user_id":"{asha}","text":"` + fmt.Sprintf(sms, "swiggy@icici") + `"}`,
+			check: all(field("kind", "expense"), hasKeys("parsed", "expense"))},
+		{name: "draft settlement from SMS", method: "POST", path: "/v1/groups/{group}/drafts/sms", status: 200,
+			body:  `{"user_id":"{asha}","text":"` + fmt.Sprintf(sms, "bala@okaxis") + `"}`,
+			check: all(field("kind", "settlement"), hasKeys("settlement"))},
+		{name: "draft without fields", method: "POST", path: "/v1/groups/{group}/drafts/sms", body: `{}`, status: 422, fields: []string{"user_id", "text"}},
+		{name: "draft from nonsense", method: "POST", path: "/v1/groups/{group}/drafts/sms", body: `{"user_id":"{asha}","text":"see you at 8"}`, status: 422, fields: []string{"text"}},
+		{name: "draft for non-member", method: "POST", path: "/v1/groups/{group}/drafts/sms", status: 403, code: "forbidden",
+			body: `{"user_id":"{missing}","text":"` + fmt.Sprintf(sms, "swiggy@icici") + `"}`},
+
+		// admin
+		{name: "audit without token", method: "GET", path: "/v1/admin/audit", status: 401, code: "unauthorized"},
+		{name: "audit", method: "GET", path: "/v1/admin/audit?group_id={group}", header: admin, status: 200, check: arrayLen(0)},
+		{name: "audit with bad cursor", method: "GET", path: "/v1/admin/audit?cursor=nope", header: admin, status: 422, fields: []string{"cursor"}},
+		{name: "verify ledger", method: "GET", path: "/v1/admin/ledger/verify", header: admin, status: 200, check: all(field("ok", true), field("issues", []any{}))},
+		{name: "journals", method: "GET", path: "/v1/admin/ledger/journals?group_id={group}&source_type=expense&source_id={expense}", header: admin, status: 200, check: arrayLen(0)},
+		{name: "journals without params", method: "GET", path: "/v1/admin/ledger/journals?source_type=bill", header: admin, status: 422,
+			fields: []string{"group_id", "source_type", "source_id"}},
+
+		// links
+		{name: "settle link", method: "POST", path: "/v1/links/settle", body: `{"to_vpa":"bala@okaxis","to_name":"Bala","amount_paise":12550,"note":"goa"}`, status: 200,
+			check: all(field("upi", "upi://pay?pa=bala%40okaxis&pn=Bala&am=125.50&cu=INR&tn=goa"),
+				field("app", "paysplit://settle?vpa=bala%40okaxis&name=Bala&amount=12550&note=goa"))},
+		{name: "settle link without fields", method: "POST", path: "/v1/links/settle", body: `{}`, status: 422, fields: []string{"to_vpa", "amount_paise"}},
+		{name: "settle link to a non-VPA", method: "POST", path: "/v1/links/settle", body: `{"to_vpa":"bala","amount_paise":100}`, status: 422, fields: []string{"to_vpa"}},
+
+		// health
+		{name: "livez", method: "GET", path: "/livez", status: 200, check: field("status", "ok")},
+		{name: "readyz without postgres", method: "GET", path: "/readyz", status: 503,
+			check: all(field("status", "unavailable"), field("checks", map[string]any{"postgres": "down", "redis": "down"}))},
+		{name: "health without postgres", method: "GET", path: "/v1/health", status: 503, check: field("status", "unavailable")},
+		{name: "metrics", method: "GET", path: "/metrics", status: 200, check: rawContains("go_goroutines")},
+		{name: "ping redis while it is down", method: "GET", path: "/v1/ping-redis", status: 500, code: "internal"},
+	}
+
+	covered := map[string]bool{}
+	for _, tt := range tests {
+		covered[tt.method+" "+routeShape(strings.SplitN(tt.path, "?", 2)[0])] = true
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			req := httptest.NewRequest(tt.method, f.expand(tt.path), strings.NewReader(f.expand(tt.body)))
+			if tt.body != "" {
+				ct := tt.contentType
+				if ct == "" {
+					ct = fiber.MIMEApplicationJSON
+				}
+				req.Header.Set(fiber.HeaderContentType, ct)
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, f.expand(v))
+			}
+			resp, err := f.app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", resp.StatusCode, tt.status, body)
+			}
+			if tt.status >= 400 && tt.check == nil {
+				checkProblem(t, resp, body, tt)
+			}
+			if tt.check != nil {
+				tt.check(t, f, resp, body)
+			}
+		})
+	}
+
+	// every route needs at least one case
+	for _, r := range newFixture(t).app.GetRoutes(true) {
+		if r.Method == fiber.MethodHead {
+			continue
+		}
+		if key := r.Method + " " + routeShape(r.Path); !covered[key] {
+			t.Errorf("no test case for %s %s", r.Method, r.Path)
+		}
+	}
+}
+
+// routeShape makes "/v1/groups/:id" and "/v1/groups/{group}" comparable.
+func routeShape(path string) string {
+	segs := strings.Split(path, "/")
+	for i, s := range segs {
+		if strings.HasPrefix(s, ":") || placeholder.MatchString(s) {
+			segs[i] = "*"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// checkProblem checks an error response is problem+json with the code, or
+// for a 422 the validation fields, the case expects.
+func checkProblem(t *testing.T, resp *http.Response, body []byte, tt routeCase) {
+	t.Helper()
+	if ct := resp.Header.Get(fiber.HeaderContentType); !strings.HasPrefix(ct, mimeProblemJSON) {
+		t.Errorf("Content-Type = %q, want %s", ct, mimeProblemJSON)
+	}
+	var p struct {
+		Status int    `json:"status"`
+		Code   string `json:"code"`
+		Errors []struct {
+			Field string `json:"field"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("problem body: %v: %s", err, body)
+	}
+	code := tt.code
+	if tt.fields != nil {
+		code = "validation_failed"
+	}
+	if p.Status != tt.status || p.Code != code {
+		t.Errorf("problem = %d %q, want %d %q; body %s", p.Status, p.Code, tt.status, code, body)
+	}
+	var fields []string
+	for _, e := range p.Errors {
+		fields = append(fields, e.Field)
+	}
+	if !slices.Equal(fields, tt.fields) {
+		t.Errorf("invalid fields = %v, want %v", fields, tt.fields)
+	}
+}
+
+// bodyCheck checks a successful response.
+type bodyCheck = func(t *testing.T, f *fixture, resp *http.Response, body []byte)
+
+func all(checks ...bodyCheck) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		for _, c := range checks {
+			c(t, f, resp, body)
+		}
+	}
+}
+
+func decode[T any](t *testing.T, body []byte) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(body, &v); err != nil {
+		t.Fatalf("decode %T: %v: %s", v, err, body)
+	}
+	return v
+}
+
+func hasKeys(keys ...string) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		obj := decode[map[string]any](t, body)
+		for _, k := range keys {
+			if _, ok := obj[k]; !ok {
+				t.Errorf("response has no %q: %s", k, body)
+			}
+		}
+	}
+}
+
+// field compares one member of the response object; string wants may use
+// fixture placeholders, an int want is the length of an array member.
+func field(key string, want any) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		obj := decode[map[string]any](t, body)
+		matchValue(t, f, f.expand(key), obj[f.expand(key)], want)
+	}
+}
+
+func matchValue(t *testing.T, f *fixture, key string, got, want any) {
+	t.Helper()
+	switch w := want.(type) {
+	case string:
+		want = f.expand(w)
+	case int:
+		arr, _ := got.([]any)
+		if len(arr) != w {
+			t.Errorf("%s has %d items, want %d", key, len(arr), w)
+		}
+		return
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s = %v, want %v", key, got, want)
+	}
+}
+
+func arrayLen(n int) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		if arr := decode[[]any](t, body); len(arr) != n {
+			t.Errorf("got %d items, want %d: %s", len(arr), n, body)
+		}
+	}
+}
+
+func first(t *testing.T, body []byte) map[string]any {
+	t.Helper()
+	arr := decode[[]map[string]any](t, body)
+	if len(arr) == 0 {
+		t.Fatalf("empty list: %s", body)
+	}
+	return arr[0]
+}
+
+func firstField(key string, want any) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		matchValue(t, f, key, first(t, body)[key], want)
+	}
+}
+
+func firstHasKeys(keys ...string) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		item := first(t, body)
+		for _, k := range keys {
+			if _, ok := item[k]; !ok {
+				t.Errorf("first item has no %q: %s", k, body)
+			}
+		}
+	}
+}
+
+func firstCandidate(id string) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		p := decode[struct {
+			Candidates []duplicateCandidate `json:"candidates"`
+		}](t, body)
+		if len(p.Candidates) == 0 || p.Candidates[0].ID != f.expand(id) {
+			t.Errorf("candidates = %+v, want %s first", p.Candidates, f.expand(id))
+		}
+	}
+}
+
+func etag(want string) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		if got := resp.Header.Get(fiber.HeaderETag); got != want {
+			t.Errorf("ETag = %q, want %q", got, want)
+		}
+	}
+}
+
+func rawBody(want string) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		if string(body) != want {
+			t.Errorf("body = %q, want %q", body, want)
+		}
+	}
+}
+
+func rawContains(want string) bodyCheck {
+	return func(t *testing.T, f *fixture, resp *http.Response, body []byte) {
+		t.Helper()
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("body has no %q", want)
+		}
+	}
+}
+
+// multipartFile is a form with the file in field "file", or no file when
+// name is empty.
+func multipartFile(name string, data []byte) (body, contentType string) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	if name != "" {
+		fw, _ := w.CreateFormFile("file", name)
+		fw.Write(data)
+	} else {
+		w.WriteField("uploaded_by", "asha")
+	}
+	w.Close()
+	return buf.String(), w.FormDataContentType()
+}
+
+func tinyPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
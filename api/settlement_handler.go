@@ -0,0 +1,177 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/metrics"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SettlementHandlers struct {
+	settlements db.SettlementStore
+	groups      db.GroupStore // both sides must be members
+	feed        *ActivityRecorder
+}
+
+func NewSettlementHandlers(settlements db.SettlementStore, groups db.GroupStore, feed *ActivityRecorder) *SettlementHandlers {
+	return &SettlementHandlers{settlements: settlements, groups: groups, feed: feed}
+}
+
+type settlementReq struct {
+	GroupID     string      `json:"group_id"` // only read by the bulk endpoint
+	FromUser    string      `json:"from_user"`
+	ToUser      string      `json:"to_user"`
+	AmountPaise types.Money `json:"amount_paise"`
+	Method      string      `json:"method"`
+	Ref         string      `json:"ref"`
+}
+
+// toSettlement validates r; field names in the error start with prefix,
+// e.g. "settlements[2]." in a bulk request.
+func (r settlementReq) toSettlement(groupID, prefix string) (*types.Settlement, error) {
+	var invalid db.ValidationError
+	if groupID == "" {
+		invalid.Add(prefix+"group_id", "required")
+	}
+	if r.FromUser == "" {
+		invalid.Add(prefix+"from_user", "required")
+	}
+	if r.ToUser == "" {
+		invalid.Add(prefix+"to_user", "required")
+	} else if r.ToUser == r.FromUser {
+		invalid.Add(prefix+"to_user", "must differ from from_user")
+	}
+	if r.AmountPaise <= 0 {
+		invalid.Add(prefix+"amount_paise", "must be > 0")
+	}
+	if err := invalid.Err(); err != nil {
+		return nil, err
+	}
+	method := strings.ToLower(strings.TrimSpace(r.Method))
+	if method == "" {
+		method = "upi"
+	}
+	return &types.Settlement{
+		GroupID:  groupID,
+		FromUser: r.FromUser,
+		ToUser:   r.ToUser,
+		Amount:   r.AmountPaise,
+		Method:   method,
+		Ref:      strings.TrimSpace(r.Ref),
+	}, nil
+}
+
+// POST /groups/:id/settlements
+func (h *SettlementHandlers) HandleCreateSettlement(c *fiber.Ctx) error {
+	var req settlementReq
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
+	}
+	st, err := req.toSettlement(c.Params("id"), "")
+	if err != nil {
+		return err
+	}
+	members, err := groupMembers(c.UserContext(), h.groups, st.GroupID)
+	if err != nil {
+		return err
+	}
+	if err := checkParties(st, members, ""); err != nil {
+		return err
+	}
+	id, err := h.settlements.Create(c.UserContext(), st)
+	if err != nil {
+		return err
+	}
+	h.recordSettlement(c, id, st)
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"id": id})
+}
+
+// GET /groups/:id/settlements
+func (h *SettlementHandlers) HandleListSettlements(c *fiber.Ctx) error {
+	out, err := h.settlements.ListByGroup(c.UserContext(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(out)
+}
+
+type bulkSettlementReq struct {
+	Settlements []settlementReq `json:"settlements"`
+}
+
+// POST /settlements/bulk
+// Confirms suggestions from a statement import in one go; all or nothing.
+func (h *SettlementHandlers) HandleBulkCreateSettlements(c *fiber.Ctx) error {
+	var req bulkSettlementReq
+	if err := c.BodyParser(&req); err != nil {
+		return errInvalidJSON
+	}
+	if len(req.Settlements) == 0 {
+		return db.Invalid("settlements", "required")
+	}
+
+	// report every bad row, not just the first
+	var invalid db.ValidationError
+	in := make([]*types.Settlement, 0, len(req.Settlements))
+	membersOf := map[string]map[string]bool{} // by group; rows often share one
+	for i, r := range req.Settlements {
+		prefix := fmt.Sprintf("settlements[%d].", i)
+		st, err := r.toSettlement(r.GroupID, prefix)
+		if err == nil {
+			members, ok := membersOf[st.GroupID]
+			if !ok {
+				if members, err = groupMembers(c.UserContext(), h.groups, st.GroupID); err != nil {
+					return err
+				}
+				membersOf[st.GroupID] = members
+			}
+			err = checkParties(st, members, prefix)
+		}
+		var ve *db.ValidationError
+		if errors.As(err, &ve) {
+			invalid.Fields = append(invalid.Fields, ve.Fields...)
+			continue
+		}
+		in = append(in, st)
+	}
+	if err := invalid.Err(); err != nil {
+		return err
+	}
+
+	ids, err := h.settlements.CreateBatch(c.UserContext(), in)
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		h.recordSettlement(c, id, in[i])
+	}
+	return c.Status(http.StatusCreated).JSON(fiber.Map{"ids": ids})
+}
+
+// checkParties is a 422 unless both sides of st are in members.
+func checkParties(st *types.Settlement, members map[string]bool, prefix string) error {
+	var invalid db.ValidationError
+	if !members[st.FromUser] {
+		invalid.Add(prefix+"from_user", notMember)
+	}
+	if !members[st.ToUser] {
+		invalid.Add(prefix+"to_user", notMember)
+	}
+	return invalid.Err()
+}
+
+func (h *SettlementHandlers) recordSettlement(c *fiber.Ctx, id string, st *types.Settlement) {
+	h.feed.record(c, st.GroupID, types.ActionSettlementCreated, "settlement", id, fiber.Map{
+		"from_user":    st.FromUser,
+		"to_user":      st.ToUser,
+		"amount_paise": st.Amount,
+		"method":       st.Method,
+	})
+	metrics.SettlementsCreated.WithLabelValues(metrics.SettlementMethod(st.Method)).Inc()
+	metrics.SettlementPaise.Add(float64(st.Amount))
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/statement"
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxStatementBytes = 5 << 20 // 5 MB is years of a personal account
+
+// maxStatementBody is the import route's body limit: the file plus multipart overhead.
+const maxStatementBody = maxStatementBytes + 1<<20
+
+type StatementHandlers struct {
+	users       db.UserStore
+	groups      db.GroupStore
+	expenses    db.ExpenseStore
+	settlements db.SettlementStore
+}
+
+func NewStatementHandlers(users db.UserStore, groups db.GroupStore, expenses db.ExpenseStore, settlements db.SettlementStore) *StatementHandlers {
+	return &StatementHandlers{users: users, groups: groups, expenses: expenses, settlements: settlements}
+}
+
+// POST /users/:id/statements/import?format=csv|ofx&window_days=30
+// multipart form, file field "file". Returns suggested settlements; nothing
+// is written until the client confirms them via POST /settlements/bulk.
+func (h *StatementHandlers) HandleImportStatement(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return db.Invalid("file", "required")
+	}
+	if fh.Size > maxStatementBytes {
+		return fiber.NewError(http.StatusRequestEntityTooLarge, "statement too large")
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = statement.FormatFromFilename(fh.Filename)
+	}
+
+	windowDays := 30
+	if v, err := strconv.Atoi(c.Query("window_days")); err == nil && v > 0 && v <= 365 {
+		windowDays = v
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, "could not read file")
+	}
+	defer f.Close()
+
+	txns, err := statement.Parse(format, f)
+	if err != nil {
+		return db.Invalid("file", err.Error())
+	}
+
+	ctx := c.UserContext()
+	if _, err := h.users.GetByID(ctx, userID); err != nil {
+		return err
+	}
+
+	// outstanding debts involving this user, across all their groups
+	groups, err := h.groups.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var debts []statement.Debt
+	known := map[string]bool{}
+	for _, g := range groups {
+		net, err := h.expenses.Balances(ctx, g.ID)
+		if err != nil {
+			return err
+		}
+		for _, t := range simplifyDebts(net) {
+			if t.From == userID || t.To == userID {
+				debts = append(debts, statement.Debt{GroupID: g.ID, From: t.From, To: t.To, AmountPaise: t.Amount})
+			}
+		}
+		settled, err := h.settlements.ListByGroup(ctx, g.ID)
+		if err != nil {
+			return err
+		}
+		for _, s := range settled {
+			if s.Ref != "" {
+				known[s.Ref] = true
+			}
+		}
+	}
+
+	// resolve counterparties by users.upi_vpa
+	owners := map[string]string{}
+	for _, t := range txns {
+		if t.VPA == "" {
+			continue
+		}
+		if _, seen := owners[t.VPA]; seen {
+			continue
+		}
+		owners[t.VPA] = ""
+		u, err := h.users.GetByUPI(ctx, t.VPA)
+		if errors.Is(err, db.ErrNotFound) {
+			continue // not on paysplit
+		}
+		if err != nil {
+			return err
+		}
+		owners[t.VPA] = u.ID
+	}
+	for vpa, id := range owners {
+		if id == "" {
+			delete(owners, vpa)
+		}
+	}
+
+	suggestions := statement.Match(userID, txns, debts, owners, statement.MatchOptions{
+		Since:     time.Now().AddDate(0, 0, -windowDays),
+		KnownRefs: known,
+	})
+	if suggestions == nil {
+		suggestions = []statement.Suggestion{}
+	}
+	return c.JSON(fiber.Map{
+		"transactions": len(txns),
+		"suggestions":  suggestions,
+	})
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+// Test doubles for the stores db has no memory version of. They keep just
+// enough state for the handlers; users, groups and expenses use db.MemoryDB.
+
+type fakeSettlementStore struct {
+	mu   sync.Mutex
+	byID map[string]*types.Settlement
+}
+
+func newFakeSettlementStore() *fakeSettlementStore {
+	return &fakeSettlementStore{byID: map[string]*types.Settlement{}}
+}
+
+func (s *fakeSettlementStore) Create(ctx context.Context, st *types.Settlement) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.ID, st.CreatedAt = uuid.New().String(), time.Now()
+	cp := *st
+	s.byID[st.ID] = &cp
+	return st.ID, nil
+}
+
+func (s *fakeSettlementStore) CreateBatch(ctx context.Context, in []*types.Settlement) ([]string, error) {
+	ids := make([]string, len(in))
+	for i, st := range in {
+		ids[i], _ = s.Create(ctx, st)
+	}
+	return ids, nil
+}
+
+func (s *fakeSettlementStore) ListByGroup(ctx context.Context, groupID string) ([]*types.Settlement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []*types.Settlement{}
+	for _, st := range s.byID {
+		if st.GroupID == groupID {
+			cp := *st
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *fakeSettlementStore) Get(ctx context.Context, groupID, id string) (*types.Settlement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.byID[id]
+	if !ok || st.GroupID != groupID {
+		return nil, db.NotFound("settlement", id)
+	}
+	cp := *st
+	return &cp, nil
+}
+
+// fakeActivityStore has no paging: any cursor is a bad one.
+type fakeActivityStore struct {
+	mu   sync.Mutex
+	list []*types.Activity
+}
+
+func (s *fakeActivityStore) Record(ctx context.Context, a *types.Activity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.ID, a.CreatedAt = uuid.New().String(), time.Now()
+	s.list = append(s.list, a)
+	return nil
+}
+
+func (s *fakeActivityStore) ListByGroup(ctx context.Context, groupID string, since *time.Time, limit int, cursor string) ([]*types.Activity, string, error) {
+	if cursor != "" {
+		return nil, "", db.ErrBadCursor
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*types.Activity
+	for i := len(s.list) - 1; i >= 0 && len(out) < limit; i-- {
+		a := s.list[i]
+		if a.GroupID == groupID && (since == nil || a.CreatedAt.After(*since)) {
+			out = append(out, a)
+		}
+	}
+	return out, "", nil
+}
+
+type fakeCommentStore struct {
+	mu   sync.Mutex
+	list []*types.Comment
+}
+
+func (s *fakeCommentStore) Create(ctx context.Context, c *types.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.ID, c.CreatedAt = uuid.New().String(), time.Now()
+	s.list = append(s.list, c)
+	return nil
+}
+
+func (s *fakeCommentStore) ListByTarget(ctx context.Context, groupID, targetType, targetID string) ([]*types.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*types.Comment
+	for _, c := range s.list {
+		if c.GroupID == groupID && c.TargetType == targetType && c.TargetID == targetID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+type fakeAttachmentStore struct {
+	mu   sync.Mutex
+	byID map[string]*types.Attachment
+}
+
+func newFakeAttachmentStore() *fakeAttachmentStore {
+	return &fakeAttachmentStore{byID: map[string]*types.Attachment{}}
+}
+
+func (s *fakeAttachmentStore) Create(ctx context.Context, a *types.Attachment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.CreatedAt, a.HasThumbnail = time.Now(), a.ThumbnailKey != ""
+	cp := *a
+	s.byID[a.ID] = &cp
+	return nil
+}
+
+func (s *fakeAttachmentStore) Get(ctx context.Context, expenseID, id string) (*types.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.byID[id]
+	if !ok || a.ExpenseID != expenseID {
+		return nil, db.NotFound("attachment", id)
+	}
+	cp := *a
+	return &cp, nil
+}
+
+func (s *fakeAttachmentStore) ListByExpense(ctx context.Context, expenseID string) ([]*types.Attachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*types.Attachment
+	for _, a := range s.byID {
+		if a.ExpenseID == expenseID {
+			cp := *a
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeAttachmentStore) Delete(ctx context.Context, expenseID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.byID[id]; !ok || a.ExpenseID != expenseID {
+		return db.NotFound("attachment", id)
+	}
+	delete(s.byID, id)
+	return nil
+}
+
+// fakeAuditStore is an empty log.
+type fakeAuditStore struct{}
+
+func (fakeAuditStore) List(ctx context.Context, f db.AuditFilter, limit int, cursor string) ([]*types.AuditEntry, string, error) {
+	if cursor != "" {
+		return nil, "", db.ErrBadCursor
+	}
+	return nil, "", nil
+}
+
+// fakeLedgerStore is a consistent ledger with nothing posted.
+type fakeLedgerStore struct{}
+
+func (fakeLedgerStore) Balances(ctx context.Context, groupID string, since int64) (map[string]types.Money, int64, error) {
+	if since == 0 {
+		return nil, 0, nil
+	}
+	return map[string]types.Money{}, 0, nil
+}
+
+func (fakeLedgerStore) Recompute(ctx context.Context, groupID string) (map[string]types.Money, error) {
+	return map[string]types.Money{}, nil
+}
+
+func (fakeLedgerStore) Journals(ctx context.Context, groupID, sourceType, sourceID string) ([]*types.Journal, error) {
+	return nil, nil
+}
+
+func (fakeLedgerStore) Verify(ctx context.Context, groupID string) ([]types.LedgerIssue, error) {
+	return []types.LedgerIssue{}, nil
+}
+
+func (fakeLedgerStore) RebuildAccounts(ctx context.Context, groupID string) error { return nil }
+
+func (fakeLedgerStore) Resync(ctx context.Context, groupID, sourceType, sourceID string) error {
+	return nil
+}
+
+var (
+	_ db.SettlementStore = (*fakeSettlementStore)(nil)
+	_ db.ActivityStore   = (*fakeActivityStore)(nil)
+	_ db.CommentStore    = (*fakeCommentStore)(nil)
+	_ db.AttachmentStore = (*fakeAttachmentStore)(nil)
+	_ db.AuditStore      = fakeAuditStore{}
+	_ db.LedgerStore     = fakeLedgerStore{}
+)
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"errors"
 	"strconv"
 	"strings"
 
@@ -22,17 +21,18 @@ func NewUserHandlers(userStore db.UserStore) *UserHandlers {
 func (h *UserHandlers) HandleCreateUser(c *fiber.Ctx) error {
 	var user types.User
 	if err := c.BodyParser(&user); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid JSON body"})
+		return errInvalidJSON
 	}
 	user.Name = strings.TrimSpace(user.Name)
 	if user.Name == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+		return db.Invalid("name", "required")
 	}
 
 	// store.Create should set user.ID (and CreatedAt) if your PG impl uses RETURNING id
-	if err := h.userStore.Create(c.Context(), &user); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create user"})
+	if err := h.userStore.Create(c.UserContext(), &user); err != nil {
+		return err
 	}
+	setETag(c, user.Version)
 	return c.Status(fiber.StatusCreated).JSON(user)
 }
 
@@ -40,13 +40,13 @@ func (h *UserHandlers) HandleCreateUser(c *fiber.Ctx) error {
 func (h *UserHandlers) HandleGetUser(c *fiber.Ctx) error {
 	userID := c.Params("id")
 	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "id is required"})
+		return db.Invalid("id", "required")
 	}
-	user, err := h.userStore.GetByID(c.Context(), userID)
+	user, err := h.userStore.GetByID(c.UserContext(), userID)
 	if err != nil {
-		// Treat not found generically (your store can return sql.ErrNoRows; map to 404)
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		return err
 	}
+	setETag(c, user.Version)
 	return c.Status(fiber.StatusOK).JSON(user)
 }
 
@@ -66,9 +66,9 @@ func (h *UserHandlers) HandleGetUsers(c *fiber.Ctx) error {
 
 	limit, offset := parseLimitOffset(c.Query("limit"), c.Query("offset"))
 
-	users, err := h.userStore.Find(c.Context(), f, limit, offset)
+	users, err := h.userStore.Find(c.UserContext(), f, limit, offset)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list users"})
+		return err
 	}
 	return c.Status(fiber.StatusOK).JSON(users)
 }
@@ -77,50 +77,60 @@ func (h *UserHandlers) HandleGetUsers(c *fiber.Ctx) error {
 func (h *UserHandlers) HandleUpdateUser(c *fiber.Ctx) error {
 	userID := c.Params("id")
 	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "id is required"})
+		return db.Invalid("id", "required")
 	}
 
 	var body types.User
 	if err := c.BodyParser(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid JSON body"})
+		return errInvalidJSON
 	}
 
-	// Optional: ensure the user exists first (nice DX)
-	if _, err := h.userStore.GetByID(c.Context(), userID); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+	cur, err := h.userStore.GetByID(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+	version, err := ifMatch(c, cur.Version)
+	if err != nil {
+		return err
 	}
 
 	// Build the update model
 	up := &types.User{
-		ID:    userID,
-		Name:  strings.TrimSpace(body.Name),
-		Email: body.Email,
-		Phone: body.Phone,
-		UPI:   body.UPI,
+		ID:      userID,
+		Name:    strings.TrimSpace(body.Name),
+		Email:   body.Email,
+		Phone:   body.Phone,
+		UPI:     body.UPI,
+		Version: version,
 	}
 	if up.Name == "" {
 		// allow partial? If name is empty and you want to keep old, you could re-fetch; here we enforce non-empty
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name is required"})
+		return db.Invalid("name", "required")
 	}
 
-	if err := h.userStore.Update(c.Context(), up); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update user"})
+	if err := h.userStore.Update(c.UserContext(), up); err != nil {
+		return err
 	}
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "user updated", "id": userID})
+	setETag(c, up.Version)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "user updated", "id": userID, "version": up.Version})
 }
 
 // DELETE /users/:id
 func (h *UserHandlers) HandleDeleteUser(c *fiber.Ctx) error {
 	userID := c.Params("id")
 	if userID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "id is required"})
+		return db.Invalid("id", "required")
 	}
-	if err := h.userStore.Delete(c.Context(), userID); err != nil {
-		// If your store returns sql.ErrNoRows on missing, map to 404
-		if errors.Is(err, fiber.ErrNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete user"})
+	cur, err := h.userStore.GetByID(c.UserContext(), userID)
+	if err != nil {
+		return err
+	}
+	version, err := ifMatch(c, cur.Version)
+	if err != nil {
+		return err
+	}
+	if err := h.userStore.Delete(c.UserContext(), userID, version); err != nil {
+		return err
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
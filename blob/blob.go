@@ -0,0 +1,19 @@
+// Package blob stores attachment bytes outside Postgres. Keys are
+// slash-separated paths ("expenses/<id>/<attachment id>") chosen by callers.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob: not found")
+
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound if the key does not exist; caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete is a no-op for missing keys
+	Delete(ctx context.Context, key string) error
+}
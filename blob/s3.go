@@ -0,0 +1,66 @@
+package blob
+
+import (
+	"context"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Store talks to any S3-compatible service: AWS S3, MinIO, R2, ...
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+type S3Options struct {
+	Endpoint  string // host[:port], e.g. "localhost:9000" for MinIO
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+}
+
+// NewS3Store connects and creates the bucket if it doesn't exist yet.
+func NewS3Store(ctx context.Context, opt S3Options) (*S3Store, error) {
+	client, err := minio.New(opt.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opt.AccessKey, opt.SecretKey, ""),
+		Secure: opt.UseSSL,
+		Region: opt.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	exists, err := client.BucketExists(ctx, opt.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, opt.Bucket, minio.MakeBucketOptions{Region: opt.Region}); err != nil {
+			return nil, err
+		}
+	}
+	return &S3Store{client: client, bucket: opt.Bucket}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	// GetObject is lazy; Stat surfaces a missing key before we start streaming
+	if _, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
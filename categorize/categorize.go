@@ -0,0 +1,97 @@
+// Package categorize guesses an expense category from its note and the
+// merchant VPA it was paid to. It is rule based on purpose: predictable,
+// cheap, and easy for a group to extend with its own keywords.
+package categorize
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+type Rule struct {
+	Category types.Category
+	Keywords []string // matched against whole words of the note
+	Handles  []string // matched as a prefix of the VPA handle (before '@')
+}
+
+// DefaultRules covers what shows up in Indian flat/trip groups.
+var DefaultRules = []Rule{
+	{types.CategoryFood, []string{"food", "dinner", "lunch", "breakfast", "snacks", "chai", "coffee", "restaurant", "cafe", "pizza", "biryani", "dosa", "swiggy", "zomato", "dominos", "mcdonalds", "kfc", "starbucks", "drinks", "beer", "party"},
+		[]string{"swiggy", "zomato", "dominos", "mcdonalds", "kfc", "starbucks", "eatsure"}},
+	{types.CategoryGroceries, []string{"groceries", "grocery", "vegetables", "sabzi", "milk", "fruits", "kirana", "bigbasket", "blinkit", "zepto", "instamart", "dmart", "jiomart"},
+		[]string{"bigbasket", "blinkit", "zepto", "instamart", "dmart", "jiomart", "grofers"}},
+	{types.CategoryTravel, []string{"travel", "trip", "cab", "taxi", "uber", "ola", "rapido", "auto", "metro", "train", "irctc", "flight", "bus", "hotel", "airbnb", "oyo", "petrol", "diesel", "fuel", "toll", "fastag", "parking", "makemytrip", "goibibo"},
+		[]string{"uber", "ola", "rapido", "irctc", "makemytrip", "goibibo", "redbus", "oyo", "airbnb", "fastag", "ixigo"}},
+	{types.CategoryRent, []string{"rent", "deposit", "brokerage", "maintenance", "society", "pg"},
+		[]string{"nobroker", "housing"}},
+	{types.CategoryUtilities, []string{"electricity", "bescom", "msedcl", "tata power", "water", "gas", "cylinder", "lpg", "wifi", "internet", "broadband", "recharge", "jio", "airtel", "vi", "dth", "tatasky", "bill"},
+		[]string{"bescom", "jio", "airtel", "tatasky", "tatapower", "bsesrajdhani", "mahadiscom", "actfibernet", "hathway"}},
+	{types.CategoryEntertainment, []string{"movie", "movies", "netflix", "prime", "hotstar", "spotify", "concert", "bookmyshow", "pvr", "inox", "game", "gaming"},
+		[]string{"bookmyshow", "pvr", "inox", "netflix", "spotify", "hotstar"}},
+	{types.CategoryShopping, []string{"shopping", "amazon", "flipkart", "myntra", "ajio", "meesho", "clothes", "gift", "decathlon"},
+		[]string{"amazon", "flipkart", "myntra", "ajio", "meesho", "decathlon"}},
+	{types.CategoryHealth, []string{"medicine", "medicines", "pharmacy", "doctor", "hospital", "clinic", "apollo", "1mg", "pharmeasy", "netmeds", "gym", "cult"},
+		[]string{"apollo", "1mg", "pharmeasy", "netmeds", "cultfit", "practo"}},
+}
+
+type Categorizer struct {
+	rules []Rule
+}
+
+func New(rules []Rule) *Categorizer {
+	return &Categorizer{rules: rules}
+}
+
+// Default is the built-in rule set with nothing group specific.
+func Default() *Categorizer {
+	return New(DefaultRules)
+}
+
+// WithCustom returns a categorizer where the group's own categories are
+// tried before the built-in ones, so "maid" can beat "other".
+func (c *Categorizer) WithCustom(custom []*types.GroupCategory) *Categorizer {
+	rules := make([]Rule, 0, len(custom)+len(c.rules))
+	for _, gc := range custom {
+		if len(gc.Keywords) > 0 {
+			rules = append(rules, Rule{Category: gc.Name, Keywords: gc.Keywords})
+		}
+	}
+	return New(append(rules, c.rules...))
+}
+
+// Guess returns the best category for an expense, CategoryOther if nothing matches.
+// A merchant VPA is a stronger signal than the note, so it is checked first.
+func (c *Categorizer) Guess(note, merchantVPA string) types.Category {
+	if handle, _, ok := strings.Cut(strings.ToLower(strings.TrimSpace(merchantVPA)), "@"); ok && handle != "" {
+		for _, r := range c.rules {
+			for _, h := range r.Handles {
+				if strings.HasPrefix(handle, h) {
+					return r.Category
+				}
+			}
+		}
+	}
+
+	words := tokenize(note)
+	if len(words) == 0 {
+		return types.CategoryOther
+	}
+	joined := " " + strings.Join(words, " ") + " "
+	for _, r := range c.rules {
+		for _, k := range r.Keywords {
+			// multi-word keywords ("tata power") match as a phrase
+			if strings.Contains(joined, " "+strings.ToLower(k)+" ") {
+				return r.Category
+			}
+		}
+	}
+	return types.CategoryOther
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
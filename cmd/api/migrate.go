@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/akarshgo/paysplit/config"
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/migrate"
+)
+
+const migrateUsage = "usage: paysplit migrate up | down [n] | status [config flags]"
+
+// runMigrate is `paysplit migrate ...`; the usual config sources apply so
+// it talks to the same database the API would.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+	steps := 1
+	if cmd == "down" && len(args) > 0 {
+		if n, err := strconv.Atoi(args[0]); err == nil {
+			steps, args = n, args[1:]
+		}
+	}
+
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	sqlDB, err := db.Open(cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer sqlDB.Close()
+	m, err := migrate.New(sqlDB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var ran []migrate.Migration
+	switch cmd {
+	case "up":
+		ran, err = m.Up(ctx)
+	case "down":
+		if steps < 1 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		ran, err = m.Down(ctx, steps)
+	case "status":
+		return printStatus(ctx, m)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	// report what did run even when a later migration failed
+	for _, mig := range ran {
+		fmt.Printf("%s %s\n", cmd, mig)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(ran) == 0 {
+		fmt.Println("nothing to do")
+	}
+	return 0
+}
+
+func printStatus(ctx context.Context, m *migrate.Migrator) int {
+	list, err := m.Status(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+	for _, st := range list {
+		applied := "pending"
+		if st.AppliedAt != nil {
+			applied = st.AppliedAt.Local().Format(time.RFC3339)
+		}
+		if st.Unknown {
+			applied += " (not in this binary)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, applied)
+	}
+	w.Flush()
+	return 0
+}
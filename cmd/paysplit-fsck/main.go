@@ -0,0 +1,106 @@
+// paysplit-fsck scans every group for data that breaks the app's
+// invariants and prints one line per problem. Meant for cron:
+//
+//	0 3 * * * paysplit-fsck -repair || alert
+//
+// Exit status: 0 clean, 1 problems left after any repairs, 2 could not run.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/akarshgo/paysplit/config"
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/fsck"
+	"github.com/akarshgo/paysplit/logger"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+// The database and logging come from the same config as the API
+// (PAYSPLIT_CONFIG / -config, DATABASE_URL, ...), so it checks what the
+// API serves.
+func run() int {
+	fs := flag.NewFlagSet("paysplit-fsck", flag.ContinueOnError)
+	var (
+		group   = fs.String("group", "", "only check this group ID")
+		repair  = fs.Bool("repair", false, "fix the safe cases (orphan splits, missing memberships, ledger drift)")
+		asJSON  = fs.Bool("json", false, "print problems as JSON")
+		timeout = fs.Duration("timeout", 10*time.Minute, "give up after this long")
+	)
+	cfg, err := config.LoadWith(fs, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fsck:", err)
+		return 2
+	}
+	zlog, err := logger.New(cfg.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fsck:", err)
+		return 2
+	}
+	defer func() {
+		_ = zlog.Sync()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ctx = db.WithAuditInfo(ctx, db.AuditInfo{ActorID: "paysplit-fsck"})
+	ctx = logger.WithContext(ctx, zlog)
+
+	sqlDB, err := db.Open(cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fsck:", err)
+		return 2
+	}
+	defer sqlDB.Close()
+
+	c := fsck.New(sqlDB, db.NewPostgresGroupStore(sqlDB), db.NewPostgresLedgerStore(sqlDB))
+	problems, err := c.Run(ctx, *group)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fsck:", err)
+		return 2
+	}
+	if *repair {
+		if problems, err = c.Repair(ctx, *group, problems); err != nil {
+			fmt.Fprintln(os.Stderr, "fsck:", err)
+			return 2
+		}
+	}
+
+	left := 0
+	for _, p := range problems {
+		if !p.Repaired {
+			left++
+		}
+	}
+
+	if *asJSON {
+		if problems == nil {
+			problems = []fsck.Problem{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(problems)
+	} else {
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		fmt.Fprintf(os.Stderr, "fsck: %d problems, %d repaired, %d remaining\n", len(problems), len(problems)-left, left)
+	}
+
+	if left > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+type ActivityStore interface {
+	Record(ctx context.Context, a *types.Activity) error
+	// ListByGroup pages the feed newest first; since limits it to entries after that time
+	ListByGroup(ctx context.Context, groupID string, since *time.Time, limit int, cursor string) ([]*types.Activity, string, error)
+}
+
+type PostgresActivityStore struct {
+	db *sql.DB
+}
+
+func NewPostgresActivityStore(db *sql.DB) *PostgresActivityStore {
+	return &PostgresActivityStore{db: db}
+}
+
+func (s *PostgresActivityStore) Record(ctx context.Context, a *types.Activity) error {
+	id := uuid.New().String()
+	now := time.Now()
+	var data any
+	if len(a.Data) > 0 {
+		data = []byte(a.Data)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO group_activity (id, group_id, actor_id, action, target_type, target_id, data, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+	`, id, a.GroupID, nullIfEmpty(a.ActorID), a.Action, nullIfEmpty(a.TargetType), nullIfEmpty(a.TargetID), data, now)
+	if err != nil {
+		return err
+	}
+	a.ID, a.CreatedAt = id, now
+	return nil
+}
+
+func (s *PostgresActivityStore) ListByGroup(ctx context.Context, groupID string, since *time.Time, limit int, cursor string) ([]*types.Activity, string, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	where := "group_id = $1"
+	args := []any{groupID}
+	if since != nil {
+		args = append(args, *since)
+		where += fmt.Sprintf(" AND created_at > $%d", len(args))
+	}
+	if cursor != "" {
+		at, id, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, at, id)
+		where += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, group_id, actor_id, action, target_type, target_id, data, created_at
+		FROM group_activity
+		WHERE `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var out []*types.Activity
+	for rows.Next() {
+		var (
+			a                 types.Activity
+			actor, ttype, tid sql.NullString
+			data              []byte
+		)
+		if err := rows.Scan(&a.ID, &a.GroupID, &actor, &a.Action, &ttype, &tid, &data, &a.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		a.ActorID, a.TargetType, a.TargetID = actor.String, ttype.String, tid.String
+		a.Data = data
+		out = append(out, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(out) > limit {
+		out = out[:limit]
+		next = EncodeCursor(out[limit-1].CreatedAt, out[limit-1].ID)
+	}
+	return out, next, nil
+}
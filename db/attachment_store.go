@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+type AttachmentStore interface {
+	// Create expects ID and keys to be set by the caller (they name the blobs)
+	Create(ctx context.Context, a *types.Attachment) error
+	Get(ctx context.Context, expenseID, id string) (*types.Attachment, error)
+	ListByExpense(ctx context.Context, expenseID string) ([]*types.Attachment, error)
+	Delete(ctx context.Context, expenseID, id string) error
+}
+
+type PostgresAttachmentStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAttachmentStore(db *sql.DB) *PostgresAttachmentStore {
+	return &PostgresAttachmentStore{db: db}
+}
+
+func (s *PostgresAttachmentStore) Create(ctx context.Context, a *types.Attachment) error {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO expense_attachments (id, expense_id, file_name, content_type, size_bytes, storage_key, thumbnail_key, uploaded_by, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, a.ID, a.ExpenseID, a.FileName, a.ContentType, a.SizeBytes, a.StorageKey, nullIfEmpty(a.ThumbnailKey), nullIfEmpty(a.UploadedBy), now)
+	if err != nil {
+		return err
+	}
+	a.CreatedAt = now
+	a.HasThumbnail = a.ThumbnailKey != ""
+	return nil
+}
+
+func (s *PostgresAttachmentStore) Get(ctx context.Context, expenseID, id string) (*types.Attachment, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, expense_id, file_name, content_type, size_bytes, storage_key, thumbnail_key, uploaded_by, created_at
+		FROM expense_attachments
+		WHERE expense_id = $1 AND id = $2
+	`, expenseID, id)
+	a, err := scanAttachment(row)
+	return a, translate(err, "attachment", id)
+}
+
+func (s *PostgresAttachmentStore) ListByExpense(ctx context.Context, expenseID string) ([]*types.Attachment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, expense_id, file_name, content_type, size_bytes, storage_key, thumbnail_key, uploaded_by, created_at
+		FROM expense_attachments
+		WHERE expense_id = $1
+		ORDER BY created_at
+	`, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*types.Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresAttachmentStore) Delete(ctx context.Context, expenseID, id string) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM expense_attachments WHERE expense_id = $1 AND id = $2
+	`, expenseID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return NotFound("attachment", id)
+	}
+	return nil
+}
+
+func scanAttachment(scanner interface{ Scan(dest ...any) error }) (*types.Attachment, error) {
+	var (
+		a       types.Attachment
+		thumbNS sql.NullString
+		byNS    sql.NullString
+	)
+	if err := scanner.Scan(&a.ID, &a.ExpenseID, &a.FileName, &a.ContentType, &a.SizeBytes, &a.StorageKey, &thumbNS, &byNS, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	a.ThumbnailKey, a.UploadedBy = thumbNS.String, byNS.String
+	a.HasThumbnail = a.ThumbnailKey != ""
+	return &a, nil
+}
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+// AuditInfo is who caused a change; stores read it from the context so
+// every mutation is attributed without threading it through signatures.
+type AuditInfo struct {
+	ActorID   string
+	RequestID string
+}
+
+type auditKey struct{}
+
+func WithAuditInfo(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditKey{}, info)
+}
+
+func AuditInfoFrom(ctx context.Context) AuditInfo {
+	info, _ := ctx.Value(auditKey{}).(AuditInfo)
+	return info
+}
+
+type AuditFilter struct {
+	GroupID   *string
+	Entity    *string
+	EntityID  *string
+	ActorID   *string
+	RequestID *string
+}
+
+type AuditStore interface {
+	// List pages newest first
+	List(ctx context.Context, f AuditFilter, limit int, cursor string) ([]*types.AuditEntry, string, error)
+}
+
+type PostgresAuditStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAuditStore(db *sql.DB) *PostgresAuditStore {
+	return &PostgresAuditStore{db: db}
+}
+
+func (s *PostgresAuditStore) List(ctx context.Context, f AuditFilter, limit int, cursor string) ([]*types.AuditEntry, string, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.GroupID != nil {
+		add("group_id = $%d", *f.GroupID)
+	}
+	if f.Entity != nil {
+		add("entity = $%d", *f.Entity)
+	}
+	if f.EntityID != nil {
+		add("entity_id = $%d", *f.EntityID)
+	}
+	if f.ActorID != nil {
+		add("actor_id = $%d", *f.ActorID)
+	}
+	if f.RequestID != nil {
+		add("request_id = $%d", *f.RequestID)
+	}
+	if cursor != "" {
+		at, id, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, at, id)
+		where = append(where, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+	q := `
+		SELECT id, actor_id, request_id, group_id, entity, entity_id, action, before, after, created_at
+		FROM audit_log`
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, limit+1)
+	q += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var out []*types.AuditEntry
+	for rows.Next() {
+		var (
+			a                   types.AuditEntry
+			actor, req, groupID sql.NullString
+			before, after       []byte
+		)
+		if err := rows.Scan(&a.ID, &actor, &req, &groupID, &a.Entity, &a.EntityID, &a.Action, &before, &after, &a.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		a.ActorID, a.RequestID, a.GroupID = actor.String, req.String, groupID.String
+		a.Before, a.After = before, after
+		out = append(out, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(out) > limit {
+		out = out[:limit]
+		next = EncodeCursor(out[limit-1].CreatedAt, out[limit-1].ID)
+	}
+	return out, next, nil
+}
+
+// --- writing ---
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// audit appends one entry using tx so it commits or rolls back with the
+// change itself. before/after are marshalled as-is; nil is stored as NULL.
+func audit(ctx context.Context, tx execer, groupID, entity, entityID, action string, before, after any) error {
+	b, err := auditJSON(before)
+	if err != nil {
+		return err
+	}
+	a, err := auditJSON(after)
+	if err != nil {
+		return err
+	}
+	info := AuditInfoFrom(ctx)
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO audit_log (id, actor_id, request_id, group_id, entity, entity_id, action, before, after, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	`, uuid.New().String(), nullIfEmpty(info.ActorID), nullIfEmpty(info.RequestID), nullIfEmpty(groupID),
+		entity, entityID, action, b, a, time.Now())
+	return err
+}
+
+func auditJSON(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// withTx runs fn in a transaction, rolling back if it returns an error
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
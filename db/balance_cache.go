@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/akarshgo/paysplit/logger"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const balanceCacheTTL = time.Hour
+
+// BalanceCache keeps each group's balance map in Redis, tagged with the
+// group's balance version. A hit is only served if its version is still
+// current, so a missed invalidation costs a reload, never a wrong answer;
+// the version check and the reload are one query. Redis being down just
+// means every read loads the totals.
+type BalanceCache struct {
+	rdb    *redis.Client
+	ledger LedgerStore
+}
+
+func NewBalanceCache(rdb *redis.Client, ledger LedgerStore) *BalanceCache {
+	return &BalanceCache{rdb: rdb, ledger: ledger}
+}
+
+type cachedBalances struct {
+	Version  int64                  `json:"v"`
+	Balances map[string]types.Money `json:"b"`
+}
+
+func balanceKey(groupID string) string { return "paysplit:balances:" + groupID }
+
+func (c *BalanceCache) Balances(ctx context.Context, groupID string) (map[string]types.Money, error) {
+	since := int64(-1)
+	cached := c.get(ctx, groupID)
+	if cached != nil && netsToZero(cached.Balances) {
+		since = cached.Version
+	}
+
+	net, version, err := c.ledger.Balances(ctx, groupID, since)
+	if err != nil {
+		return nil, err
+	}
+	if net == nil {
+		return cached.Balances, nil // still current
+	}
+	// totals that don't net to zero mean the projection is off: check it
+	// against the postings themselves
+	if !netsToZero(net) {
+		if net, err = c.recompute(ctx, groupID, net); err != nil {
+			return nil, err
+		}
+	}
+	c.set(ctx, groupID, &cachedBalances{Version: version, Balances: net})
+	return net, nil
+}
+
+// Invalidate drops the group's entry after a write. Best effort: the
+// version check catches anything this misses.
+func (c *BalanceCache) Invalidate(ctx context.Context, groupID string) {
+	if c.rdb == nil {
+		return
+	}
+	if err := c.rdb.Del(ctx, balanceKey(groupID)).Err(); err != nil {
+		logger.From(ctx).Warn("balance cache invalidate failed", zap.String("group_id", groupID), zap.Error(err))
+	}
+}
+
+// recompute sums the postings from scratch. If the account totals disagree
+// the postings win, and the drift is logged for the fsck to look at.
+func (c *BalanceCache) recompute(ctx context.Context, groupID string, projected map[string]types.Money) (map[string]types.Money, error) {
+	full, err := c.ledger.Recompute(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameBalances(full, projected) {
+		logger.From(ctx).Error("balance projection drift", zap.String("group_id", groupID),
+			zap.Any("accounts", projected), zap.Any("postings", full))
+	}
+	return full, nil
+}
+
+func (c *BalanceCache) get(ctx context.Context, groupID string) *cachedBalances {
+	if c.rdb == nil {
+		return nil
+	}
+	b, err := c.rdb.Get(ctx, balanceKey(groupID)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.From(ctx).Warn("balance cache read failed", zap.String("group_id", groupID), zap.Error(err))
+		}
+		return nil
+	}
+	var out cachedBalances
+	if err := json.Unmarshal(b, &out); err != nil {
+		return &cachedBalances{Version: -1} // garbage counts as stale
+	}
+	return &out
+}
+
+func (c *BalanceCache) set(ctx context.Context, groupID string, v *cachedBalances) {
+	if c.rdb == nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	if err := c.rdb.Set(ctx, balanceKey(groupID), b, balanceCacheTTL).Err(); err != nil {
+		logger.From(ctx).Warn("balance cache write failed", zap.String("group_id", groupID), zap.Error(err))
+	}
+}
+
+func netsToZero(net map[string]types.Money) bool {
+	var sum types.Money
+	var err error
+	for _, v := range net {
+		if sum, err = sum.Add(v); err != nil {
+			return false
+		}
+	}
+	return sum == 0
+}
+
+// sameBalances compares ignoring zero entries (a settled account may or
+// may not have a row)
+func sameBalances(a, b map[string]types.Money) bool {
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	for k, v := range b {
+		if a[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// --- store wrappers ---
+
+// CachedExpenseStore serves Balances through the cache and invalidates it
+// on every write; everything else goes straight to the wrapped store.
+type CachedExpenseStore struct {
+	ExpenseStore
+	cache *BalanceCache
+}
+
+func NewCachedExpenseStore(inner ExpenseStore, cache *BalanceCache) *CachedExpenseStore {
+	return &CachedExpenseStore{ExpenseStore: inner, cache: cache}
+}
+
+func (s *CachedExpenseStore) Create(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) (string, error) {
+	id, err := s.ExpenseStore.Create(ctx, e, splits)
+	if err == nil {
+		s.cache.Invalidate(ctx, e.GroupID)
+	}
+	return id, err
+}
+
+func (s *CachedExpenseStore) Update(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) error {
+	err := s.ExpenseStore.Update(ctx, e, splits)
+	if err == nil {
+		s.cache.Invalidate(ctx, e.GroupID)
+	}
+	return err
+}
+
+func (s *CachedExpenseStore) Delete(ctx context.Context, groupID, id string, version int64) error {
+	err := s.ExpenseStore.Delete(ctx, groupID, id, version)
+	if err == nil {
+		s.cache.Invalidate(ctx, groupID)
+	}
+	return err
+}
+
+func (s *CachedExpenseStore) Balances(ctx context.Context, groupID string) (map[string]types.Money, error) {
+	return s.cache.Balances(ctx, groupID)
+}
+
+type CachedSettlementStore struct {
+	SettlementStore
+	cache *BalanceCache
+}
+
+func NewCachedSettlementStore(inner SettlementStore, cache *BalanceCache) *CachedSettlementStore {
+	return &CachedSettlementStore{SettlementStore: inner, cache: cache}
+}
+
+func (s *CachedSettlementStore) Create(ctx context.Context, st *types.Settlement) (string, error) {
+	id, err := s.SettlementStore.Create(ctx, st)
+	if err == nil {
+		s.cache.Invalidate(ctx, st.GroupID)
+	}
+	return id, err
+}
+
+func (s *CachedSettlementStore) CreateBatch(ctx context.Context, in []*types.Settlement) ([]string, error) {
+	ids, err := s.SettlementStore.CreateBatch(ctx, in)
+	if err == nil {
+		seen := map[string]bool{}
+		for _, st := range in {
+			if !seen[st.GroupID] {
+				seen[st.GroupID] = true
+				s.cache.Invalidate(ctx, st.GroupID)
+			}
+		}
+	}
+	return ids, err
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+type CommentStore interface {
+	Create(ctx context.Context, c *types.Comment) error
+	ListByTarget(ctx context.Context, groupID, targetType, targetID string) ([]*types.Comment, error)
+}
+
+type PostgresCommentStore struct {
+	db *sql.DB
+}
+
+func NewPostgresCommentStore(db *sql.DB) *PostgresCommentStore {
+	return &PostgresCommentStore{db: db}
+}
+
+func (s *PostgresCommentStore) Create(ctx context.Context, c *types.Comment) error {
+	id := uuid.New().String()
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO comments (id, group_id, target_type, target_id, author_id, body, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+	`, id, c.GroupID, c.TargetType, c.TargetID, c.AuthorID, c.Body, now)
+	if err != nil {
+		return err
+	}
+	c.ID, c.CreatedAt = id, now
+	return nil
+}
+
+// ListByTarget returns the thread oldest first, like a chat
+func (s *PostgresCommentStore) ListByTarget(ctx context.Context, groupID, targetType, targetID string) ([]*types.Comment, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, group_id, target_type, target_id, author_id, body, created_at
+		FROM comments
+		WHERE group_id = $1 AND target_type = $2 AND target_id = $3
+		ORDER BY created_at, id
+	`, groupID, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*types.Comment
+	for rows.Next() {
+		var (
+			c      types.Comment
+			author sql.NullString
+		)
+		if err := rows.Scan(&c.ID, &c.GroupID, &c.TargetType, &c.TargetID, &author, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.AuthorID = author.String
+		out = append(out, &c)
+	}
+	return out, rows.Err()
+}
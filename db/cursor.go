@@ -0,0 +1,40 @@
+package db
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrBadCursor error = &ValidationError{Fields: []FieldError{{Field: "cursor", Message: "invalid cursor"}}}
+
+// Cursors are opaque to clients: base64("<created_at>|<id>"). Paging on
+// (created_at, id) keeps pages stable when rows share a timestamp and
+// stays an index range scan however deep the client pages.
+
+func EncodeCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(c string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return time.Time{}, "", ErrBadCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", ErrBadCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", ErrBadCursor
+	}
+	// the id goes into the keyset comparison, Postgres would refuse a non-UUID
+	if _, err := uuid.Parse(id); err != nil {
+		return time.Time{}, "", ErrBadCursor
+	}
+	return t, id, nil
+}
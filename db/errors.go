@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Domain errors. Stores return these instead of driver errors so handlers
+// can pass them straight up; api.ErrorHandler maps each kind to a status
+// and a stable code.
+
+var ErrNotFound = errors.New("not found")
+
+type NotFoundError struct {
+	Entity string // "user", "expense", ...
+	ID     string
+}
+
+func NotFound(entity, id string) error {
+	return &NotFoundError{Entity: entity, ID: id}
+}
+
+func (e *NotFoundError) Error() string { return e.Entity + " not found" }
+
+// Is matches ErrNotFound, and sql.ErrNoRows for callers written before
+// the stores translated it.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound || target == sql.ErrNoRows
+}
+
+// ConflictError is a request that can't apply to the current state.
+type ConflictError struct {
+	Code    string // stable, e.g. "version_conflict", "already_exists"
+	Message string
+}
+
+func (e *ConflictError) Error() string { return e.Message }
+
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists everything wrong with the input at once.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func Invalid(field, msg string) error {
+	return &ValidationError{Fields: []FieldError{{Field: field, Message: msg}}}
+}
+
+// Add records a problem with field; check Err once everything is.
+func (e *ValidationError) Add(field, msg string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: msg})
+}
+
+// Err is e, or nil if nothing was added.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Message
+		if f.Field != "" {
+			parts[i] = f.Field + ": " + f.Message
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+type ForbiddenError struct {
+	Message string
+}
+
+func Forbidden(msg string) error { return &ForbiddenError{Message: msg} }
+
+func (e *ForbiddenError) Error() string { return e.Message }
+
+// translate turns driver errors into domain errors. entity and id say what
+// was being read or written, so a missing row becomes a useful NotFound.
+func translate(err error, entity, id string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound(entity, id)
+	}
+	var pe *pq.Error
+	if !errors.As(err, &pe) {
+		return err
+	}
+	field := constraintField(pe)
+	switch pe.Code.Name() {
+	case "unique_violation":
+		return &ConflictError{Code: "already_exists", Message: field + " already in use"}
+	case "foreign_key_violation":
+		if strings.HasPrefix(pe.Message, "update or delete") {
+			return &ConflictError{Code: "in_use", Message: fmt.Sprintf("%s is still referenced by %s", entity, pe.Table)}
+		}
+		return Invalid(field, "does not exist")
+	case "check_violation", "not_null_violation":
+		if pe.Column != "" {
+			field = pe.Column
+		}
+		return Invalid(field, "invalid value")
+	case "invalid_text_representation":
+		// a malformed UUID can't name a row that exists
+		if id != "" {
+			return NotFound(entity, id)
+		}
+		return Invalid("", "malformed id")
+	}
+	return err
+}
+
+// constraintField guesses the column from Postgres' default constraint
+// names: users_email_key, groups_created_by_fkey, ...
+func constraintField(pe *pq.Error) string {
+	f := strings.TrimPrefix(pe.Constraint, pe.Table+"_")
+	for _, suffix := range []string{"_key", "_fkey", "_check"} {
+		f = strings.TrimSuffix(f, suffix)
+	}
+	return f
+}
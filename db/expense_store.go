@@ -3,16 +3,40 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/akarshgo/paysplit/types"
 	"github.com/google/uuid"
 )
 
+type ExpenseFilter struct {
+	From        *time.Time // created_at >= From
+	To          *time.Time // created_at < To
+	PaidBy      *string
+	Participant *string // has a split row for this user
+	Category    *types.Category
+	MinAmount   *types.Money
+	MaxAmount   *types.Money
+	Search      *string // full-text over note
+}
+
 type ExpenseStore interface {
 	Create(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) (string, error)
-	ListByGroup(ctx context.Context, groupID string) ([]*types.Expense, error)
-	Balances(ctx context.Context, groupID string) (map[string]int64, error)
+	// ListByGroup pages newest first; pass the returned cursor back to get the next page ("" when done)
+	ListByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.Expense, string, error)
+	ListDetailsByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.ExpenseDetail, string, error)
+	Get(ctx context.Context, groupID, id string) (*types.ExpenseDetail, error)
+	// Update rewrites the expense row and replaces its splits if e.Version is
+	// still current (ErrVersionConflict otherwise), bumping e.Version; ErrNotFound if missing
+	Update(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) error
+	// Delete removes the expense with its splits and attachment rows if version
+	// is still current; ErrNotFound if missing
+	Delete(ctx context.Context, groupID, id string, version int64) error
+	Balances(ctx context.Context, groupID string) (map[string]types.Money, error)
+	TotalsByCategory(ctx context.Context, groupID string, f ExpenseFilter) ([]types.CategoryTotal, error)
 }
 
 type PostgresExpenseStore struct {
@@ -25,6 +49,11 @@ func NewPostgresExpenseStore(db *sql.DB) *PostgresExpenseStore {
 
 // Create inserts expense + splits in one transaction
 func (s *PostgresExpenseStore) Create(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) (string, error) {
+	id, err := s.create(ctx, e, splits)
+	return id, translate(err, "expense", "")
+}
+
+func (s *PostgresExpenseStore) create(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) (string, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return "", err
@@ -39,9 +68,9 @@ func (s *PostgresExpenseStore) Create(ctx context.Context, e *types.Expense, spl
 	now := time.Now()
 
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO expenses (id, group_id, paid_by, amount_paise, currency, note, split_kind, created_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-	`, id, e.GroupID, e.PaidBy, e.AmountPaise, e.Currency, e.Note, string(e.SplitKind), now)
+		INSERT INTO expenses (id, group_id, paid_by, amount_paise, currency, note, category, merchant_vpa, split_kind, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+	`, id, e.GroupID, e.PaidBy, e.AmountPaise, e.Currency, e.Note, string(e.Category), nullIfEmpty(e.MerchantVPA), string(e.SplitKind), now)
 	if err != nil {
 		return "", err
 	}
@@ -56,61 +85,394 @@ func (s *PostgresExpenseStore) Create(ctx context.Context, e *types.Expense, spl
 		}
 	}
 
+	want, err := expenseLedger(e.PaidBy, splits)
+	if err != nil {
+		return "", err
+	}
+	if err = syncLedger(ctx, tx, e.GroupID, "expense", id, "expense created", want); err != nil {
+		return "", err
+	}
+
+	after := expenseSnapshot{Expense: *e, Splits: splits}
+	after.ID, after.Version, after.CreatedAt = id, 1, now
+	if err = audit(ctx, tx, e.GroupID, "expense", id, "create", nil, after); err != nil {
+		return "", err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return "", err
 	}
 
 	e.ID = id
+	e.Version = 1
 	e.CreatedAt = now
 	return id, nil
 }
 
-// ListByGroup fetches all expenses for a group
-func (s *PostgresExpenseStore) ListByGroup(ctx context.Context, groupID string) ([]*types.Expense, error) {
+// ListByGroup fetches one page of a group's expenses matching f
+func (s *PostgresExpenseStore) ListByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.Expense, string, error) {
+	where, args, limit, err := f.page(groupID, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, group_id, paid_by, amount_paise, currency, note, split_kind, created_at
+		SELECT `+expenseCols+`
 		FROM expenses
-		WHERE group_id = $1
-		ORDER BY created_at DESC
-	`, groupID)
+		WHERE `+where, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "group", groupID)
 	}
 	defer rows.Close()
 
 	var out []*types.Expense
 	for rows.Next() {
-		var e types.Expense
-		if err := rows.Scan(&e.ID, &e.GroupID, &e.PaidBy, &e.AmountPaise, &e.Currency, &e.Note, &e.SplitKind, &e.CreatedAt); err != nil {
+		e, err := scanExpense(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(out) > limit {
+		out = out[:limit]
+		next = EncodeCursor(out[limit-1].CreatedAt, out[limit-1].ID)
+	}
+	return out, next, nil
+}
+
+// ListDetailsByGroup is ListByGroup with splits and display names embedded.
+// Splits are aggregated in the same query so a page costs one round trip.
+func (s *PostgresExpenseStore) ListDetailsByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.ExpenseDetail, string, error) {
+	where, args, limit, err := f.page(groupID, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+expenseCols+`, `+detailCols+`
+		FROM expenses
+		LEFT JOIN users payer ON payer.id = expenses.paid_by
+		WHERE `+where, args...)
+	if err != nil {
+		return nil, "", translate(err, "group", groupID)
+	}
+	defer rows.Close()
+
+	var out []*types.ExpenseDetail
+	for rows.Next() {
+		d, err := scanExpenseDetail(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(out) > limit {
+		out = out[:limit]
+		next = EncodeCursor(out[limit-1].CreatedAt, out[limit-1].ID)
+	}
+	return out, next, nil
+}
+
+// Get returns one expense of the group with its splits; ErrNotFound if missing
+func (s *PostgresExpenseStore) Get(ctx context.Context, groupID, id string) (*types.ExpenseDetail, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+expenseCols+`, `+detailCols+`
+		FROM expenses
+		LEFT JOIN users payer ON payer.id = expenses.paid_by
+		WHERE expenses.group_id = $1 AND expenses.id = $2
+	`, groupID, id)
+	d, err := scanExpenseDetail(row)
+	return d, translate(err, "expense", id)
+}
+
+func (s *PostgresExpenseStore) Update(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) error {
+	return translate(s.update(ctx, e, splits), "expense", e.ID)
+}
+
+func (s *PostgresExpenseStore) update(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	before, err := lockExpense(ctx, tx, e.GroupID, e.ID)
+	if err != nil {
+		return err
+	}
+	if before.Version != e.Version {
+		err = ErrVersionConflict
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE expenses
+		SET paid_by = $3, amount_paise = $4, note = $5, category = $6, merchant_vpa = $7, split_kind = $8,
+			version = version + 1
+		WHERE group_id = $1 AND id = $2
+	`, e.GroupID, e.ID, e.PaidBy, e.AmountPaise, e.Note, string(e.Category), nullIfEmpty(e.MerchantVPA), string(e.SplitKind))
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM expense_splits WHERE expense_id = $1`, e.ID); err != nil {
+		return err
+	}
+	for _, sp := range splits {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO expense_splits (id, expense_id, user_id, exact)
+			VALUES ($1,$2,$3,$4)
+		`, uuid.New().String(), e.ID, sp.UserID, sp.Exact)
+		if err != nil {
+			return err
+		}
+	}
+
+	want, err := expenseLedger(e.PaidBy, splits)
+	if err != nil {
+		return err
+	}
+	if err = syncLedger(ctx, tx, e.GroupID, "expense", e.ID, "expense updated", want); err != nil {
+		return err
+	}
+
+	after := expenseSnapshot{Expense: *e, Splits: splits}
+	after.Version, after.CreatedAt = before.Version+1, before.CreatedAt
+	if err = audit(ctx, tx, e.GroupID, "expense", e.ID, "update", before, after); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	e.Version = after.Version
+	return nil
+}
+
+func (s *PostgresExpenseStore) Delete(ctx context.Context, groupID, id string, version int64) error {
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := lockExpense(ctx, tx, groupID, id)
+		if err != nil {
+			return err
+		}
+		if before.Version != version {
+			return ErrVersionConflict
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM expenses WHERE group_id = $1 AND id = $2
+		`, groupID, id); err != nil {
+			return err
+		}
+		if err := syncLedger(ctx, tx, groupID, "expense", id, "expense deleted", nil); err != nil {
+			return err
+		}
+		return audit(ctx, tx, groupID, "expense", id, "delete", before, nil)
+	})
+	return translate(err, "expense", id)
+}
+
+// TotalsByCategory sums expense amounts per category, biggest first
+func (s *PostgresExpenseStore) TotalsByCategory(ctx context.Context, groupID string, f ExpenseFilter) ([]types.CategoryTotal, error) {
+	where, args := f.where(groupID)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT category, COUNT(*), COALESCE(SUM(amount_paise), 0)
+		FROM expenses
+		WHERE `+where+`
+		GROUP BY category
+		ORDER BY 3 DESC, category
+	`, args...)
+	if err != nil {
+		return nil, translate(err, "group", groupID)
+	}
+	defer rows.Close()
+
+	out := []types.CategoryTotal{}
+	for rows.Next() {
+		var t types.CategoryTotal
+		if err := rows.Scan(&t.Category, &t.Count, &t.Total); err != nil {
 			return nil, err
 		}
-		out = append(out, &e)
+		out = append(out, t)
 	}
 	return out, rows.Err()
 }
 
-// Balances computes net balance per user in group
-func (s *PostgresExpenseStore) Balances(ctx context.Context, groupID string) (map[string]int64, error) {
+// Balances reads each member's account total from the ledger; positive
+// means the group owes them.
+func (s *PostgresExpenseStore) Balances(ctx context.Context, groupID string) (map[string]types.Money, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT e.paid_by, s.user_id, s.exact
-		FROM expenses e
-		JOIN expense_splits s ON s.expense_id = e.id
-		WHERE e.group_id = $1
+		SELECT user_id, balance
+		FROM ledger_accounts
+		WHERE group_id = $1
 	`, groupID)
 	if err != nil {
-		return nil, err
+		return nil, translate(err, "group", groupID)
 	}
 	defer rows.Close()
 
-	net := map[string]int64{}
+	net := map[string]types.Money{}
 	for rows.Next() {
-		var paidBy, userID string
-		var exact int64
-		if err := rows.Scan(&paidBy, &userID, &exact); err != nil {
+		var userID string
+		var balance types.Money
+		if err := rows.Scan(&userID, &balance); err != nil {
 			return nil, err
 		}
-		net[paidBy] += exact // payer gets credit
-		net[userID] -= exact // participant owes
+		net[userID] = balance
 	}
 	return net, rows.Err()
 }
+
+// where builds the WHERE clause shared by list and report queries.
+// Columns are qualified since the detail queries join users.
+func (f ExpenseFilter) where(groupID string) (string, []any) {
+	where := []string{"expenses.group_id = $1"}
+	args := []any{groupID}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.From != nil {
+		add("expenses.created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("expenses.created_at < $%d", *f.To)
+	}
+	if f.PaidBy != nil {
+		add("expenses.paid_by = $%d", *f.PaidBy)
+	}
+	if f.Participant != nil {
+		add("EXISTS (SELECT 1 FROM expense_splits sp WHERE sp.expense_id = expenses.id AND sp.user_id = $%d)", *f.Participant)
+	}
+	if f.Category != nil {
+		add("expenses.category = $%d", string(*f.Category))
+	}
+	if f.MinAmount != nil {
+		add("expenses.amount_paise >= $%d", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		add("expenses.amount_paise <= $%d", *f.MaxAmount)
+	}
+	if f.Search != nil && strings.TrimSpace(*f.Search) != "" {
+		add("expenses.note_tsv @@ plainto_tsquery('simple', $%d)", strings.TrimSpace(*f.Search))
+	}
+	return strings.Join(where, " AND "), args
+}
+
+// page extends where with the keyset cursor, ordering and LIMIT. One extra
+// row is fetched so the caller knows whether there is a next page.
+func (f ExpenseFilter) page(groupID string, limit int, cursor string) (string, []any, int, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	where, args := f.where(groupID)
+	if cursor != "" {
+		at, id, err := DecodeCursor(cursor)
+		if err != nil {
+			return "", nil, 0, err
+		}
+		args = append(args, at, id)
+		where += fmt.Sprintf(" AND (expenses.created_at, expenses.id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	args = append(args, limit+1)
+	where += fmt.Sprintf(" ORDER BY expenses.created_at DESC, expenses.id DESC LIMIT $%d", len(args))
+	return where, args, limit, nil
+}
+
+// --- helpers ---
+
+const expenseCols = `expenses.id, expenses.group_id, expenses.paid_by, expenses.amount_paise, expenses.currency,
+	expenses.note, expenses.category, expenses.merchant_vpa, expenses.split_kind, expenses.version, expenses.created_at`
+
+// payer name + splits with participant names as a JSON array
+const detailCols = `COALESCE(payer.name, ''),
+	COALESCE((
+		SELECT json_agg(json_build_object(
+			'id', sp.id, 'user_id', sp.user_id, 'exact', sp.exact, 'user_name', COALESCE(u.name, ''))
+			ORDER BY sp.exact DESC, sp.user_id)
+		FROM expense_splits sp
+		LEFT JOIN users u ON u.id = sp.user_id
+		WHERE sp.expense_id = expenses.id
+	), '[]')`
+
+// expenseSnapshot is how an expense is recorded in the audit log
+type expenseSnapshot struct {
+	types.Expense
+	Splits []types.ExpenseSplit `json:"splits"`
+}
+
+// lockExpense reads an expense and its splits inside tx, locking the row
+// until the tx ends; sql.ErrNoRows if missing.
+func lockExpense(ctx context.Context, tx *sql.Tx, groupID, id string) (*expenseSnapshot, error) {
+	e, err := scanExpense(tx.QueryRowContext(ctx, `
+		SELECT `+expenseCols+`
+		FROM expenses
+		WHERE expenses.group_id = $1 AND expenses.id = $2
+		FOR UPDATE
+	`, groupID, id))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, user_id, exact FROM expense_splits
+		WHERE expense_id = $1
+		ORDER BY exact DESC, user_id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snap := &expenseSnapshot{Expense: *e, Splits: []types.ExpenseSplit{}}
+	for rows.Next() {
+		sp := types.ExpenseSplit{ExpenseID: id}
+		if err := rows.Scan(&sp.ID, &sp.UserID, &sp.Exact); err != nil {
+			return nil, err
+		}
+		snap.Splits = append(snap.Splits, sp)
+	}
+	return snap, rows.Err()
+}
+
+func scanExpense(scanner interface{ Scan(dest ...any) error }, extra ...any) (*types.Expense, error) {
+	var (
+		e      types.Expense
+		vpaNS  sql.NullString
+		noteNS sql.NullString
+	)
+	dest := append([]any{&e.ID, &e.GroupID, &e.PaidBy, &e.AmountPaise, &e.Currency, &noteNS, &e.Category, &vpaNS, &e.SplitKind, &e.Version, &e.CreatedAt}, extra...)
+	if err := scanner.Scan(dest...); err != nil {
+		return nil, err
+	}
+	e.Note, e.MerchantVPA = noteNS.String, vpaNS.String
+	return &e, nil
+}
+
+func scanExpenseDetail(scanner interface{ Scan(dest ...any) error }) (*types.ExpenseDetail, error) {
+	var (
+		d      types.ExpenseDetail
+		splits []byte
+	)
+	e, err := scanExpense(scanner, &d.PaidByName, &splits)
+	if err != nil {
+		return nil, err
+	}
+	d.Expense = *e
+	if err := json.Unmarshal(splits, &d.Splits); err != nil {
+		return nil, err
+	}
+	for i := range d.Splits {
+		d.Splits[i].ExpenseID = e.ID
+	}
+	return &d, nil
+}
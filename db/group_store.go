@@ -3,16 +3,25 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/akarshgo/paysplit/types"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type GroupStore interface {
 	Create(ctx context.Context, g *types.Group) (string, error)
+	Get(ctx context.Context, id string) (*types.Group, error)
+	// Update renames the group if g.Version is still current (ErrVersionConflict
+	// otherwise) and bumps g.Version; ErrNotFound if missing
+	Update(ctx context.Context, g *types.Group) error
 	ListByUser(ctx context.Context, userID string) ([]*types.Group, error)
 	AddMember(ctx context.Context, groupID, userID string) error
+	ListMembers(ctx context.Context, groupID string) ([]string, error) // user IDs
+	AddCategory(ctx context.Context, c *types.GroupCategory) error
+	ListCategories(ctx context.Context, groupID string) ([]*types.GroupCategory, error)
 }
 
 type PostgresGroupStore struct {
@@ -27,28 +36,82 @@ func (p *PostgresGroupStore) Create(ctx context.Context, g *types.Group) (string
 	id := uuid.New().String()
 	now := time.Now()
 
-	_, err := p.db.ExecContext(ctx, `
-		INSERT INTO groups (id, name, created_by, created_at)
-		VALUES ($1, $2, $3, $4)
-	`, id, g.Name, g.CreatedBy, now)
+	err := withTx(ctx, p.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO groups (id, name, created_by, created_at)
+			VALUES ($1, $2, $3, $4)
+		`, id, g.Name, g.CreatedBy, now)
+		if err != nil {
+			return err
+		}
+
+		// also insert creator as member (admin)
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO group_members (group_id, user_id, role, added_at)
+			VALUES ($1, $2, 'admin', $3)
+			ON CONFLICT DO NOTHING
+		`, id, g.CreatedBy, now)
+		if err != nil {
+			return err
+		}
+
+		after := *g
+		after.ID, after.Version, after.CreatedAt = id, 1, now
+		if err := audit(ctx, tx, id, "group", id, "create", nil, after); err != nil {
+			return err
+		}
+		return audit(ctx, tx, id, "group_member", g.CreatedBy, "create", nil, memberSnapshot{id, g.CreatedBy, "admin"})
+	})
 	if err != nil {
-		return "", err
+		return "", translate(err, "group", "")
 	}
 
-	// also insert creator as member (admin)
-	_, _ = p.db.ExecContext(ctx, `
-		INSERT INTO group_members (group_id, user_id, role, added_at)
-		VALUES ($1, $2, 'admin', $3)
-		ON CONFLICT DO NOTHING
-	`, id, g.CreatedBy, now)
-
-	g.ID, g.CreatedAt = id, now
+	g.ID, g.Version, g.CreatedAt = id, 1, now
 	return id, nil
 }
 
+func (p *PostgresGroupStore) Get(ctx context.Context, id string) (*types.Group, error) {
+	row := p.db.QueryRowContext(ctx, `
+		SELECT id, name, created_by, version, created_at
+		FROM groups WHERE id = $1
+	`, id)
+	g, err := scanGroup(row)
+	return g, translate(err, "group", id)
+}
+
+func (p *PostgresGroupStore) Update(ctx context.Context, g *types.Group) error {
+	var after types.Group
+	err := withTx(ctx, p.db, func(tx *sql.Tx) error {
+		before, err := scanGroup(tx.QueryRowContext(ctx, `
+			SELECT id, name, created_by, version, created_at
+			FROM groups WHERE id = $1
+			FOR UPDATE
+		`, g.ID))
+		if err != nil {
+			return err
+		}
+		if before.Version != g.Version {
+			return ErrVersionConflict
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE groups SET name = $2, version = version + 1 WHERE id = $1
+		`, g.ID, g.Name); err != nil {
+			return err
+		}
+		after = *before
+		after.Name, after.Version = g.Name, before.Version+1
+		return audit(ctx, tx, g.ID, "group", g.ID, "update", before, after)
+	})
+	if err != nil {
+		return translate(err, "group", g.ID)
+	}
+	*g = after
+	return nil
+}
+
 func (p *PostgresGroupStore) ListByUser(ctx context.Context, userID string) ([]*types.Group, error) {
 	rows, err := p.db.QueryContext(ctx, `
-		SELECT g.id, g.name, g.created_by, g.created_at
+		SELECT g.id, g.name, g.created_by, g.version, g.created_at
 		FROM groups g
 		JOIN group_members m ON m.group_id = g.id
 		WHERE m.user_id = $1
@@ -61,20 +124,132 @@ func (p *PostgresGroupStore) ListByUser(ctx context.Context, userID string) ([]*
 
 	var out []*types.Group
 	for rows.Next() {
-		var g types.Group
-		if err := rows.Scan(&g.ID, &g.Name, &g.CreatedBy, &g.CreatedAt); err != nil {
+		g, err := scanGroup(rows)
+		if err != nil {
 			return nil, err
 		}
-		out = append(out, &g)
+		out = append(out, g)
 	}
 	return out, rows.Err()
 }
 
 func (p *PostgresGroupStore) AddMember(ctx context.Context, groupID, userID string) error {
-	_, err := p.db.ExecContext(ctx, `
-		INSERT INTO group_members (group_id, user_id, role, added_at)
-		VALUES ($1, $2, 'member', $3)
-		ON CONFLICT DO NOTHING
-	`, groupID, userID, time.Now())
-	return err
+	err := withTx(ctx, p.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO group_members (group_id, user_id, role, added_at)
+			VALUES ($1, $2, 'member', $3)
+			ON CONFLICT DO NOTHING
+		`, groupID, userID, time.Now())
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil // already a member, nothing changed
+		}
+		return audit(ctx, tx, groupID, "group_member", userID, "create", nil, memberSnapshot{groupID, userID, "member"})
+	})
+	return translate(err, "group", "")
+}
+
+func (p *PostgresGroupStore) ListMembers(ctx context.Context, groupID string) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT user_id FROM group_members
+		WHERE group_id = $1
+		ORDER BY added_at
+	`, groupID)
+	if err != nil {
+		return nil, translate(err, "group", groupID)
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+func (p *PostgresGroupStore) AddCategory(ctx context.Context, c *types.GroupCategory) error {
+	now := time.Now()
+	createdAt := now
+	err := withTx(ctx, p.db, func(tx *sql.Tx) error {
+		var before *types.GroupCategory
+		prev := types.GroupCategory{GroupID: c.GroupID, Name: c.Name}
+		err := tx.QueryRowContext(ctx, `
+			SELECT keywords, created_at FROM group_categories
+			WHERE group_id = $1 AND name = $2
+			FOR UPDATE
+		`, c.GroupID, string(c.Name)).Scan(pq.Array(&prev.Keywords), &prev.CreatedAt)
+		switch {
+		case err == nil:
+			before = &prev
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO group_categories (group_id, name, keywords, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (group_id, name) DO UPDATE SET keywords = EXCLUDED.keywords
+		`, c.GroupID, string(c.Name), pq.Array(c.Keywords), now)
+		if err != nil {
+			return err
+		}
+
+		after := *c
+		after.CreatedAt = now
+		if before == nil {
+			return audit(ctx, tx, c.GroupID, "group_category", string(c.Name), "create", nil, after)
+		}
+		createdAt = before.CreatedAt // an upsert keeps the original row
+		after.CreatedAt = createdAt
+		return audit(ctx, tx, c.GroupID, "group_category", string(c.Name), "update", before, after)
+	})
+	if err != nil {
+		return translate(err, "group", "")
+	}
+	c.CreatedAt = createdAt
+	return nil
+}
+
+func (p *PostgresGroupStore) ListCategories(ctx context.Context, groupID string) ([]*types.GroupCategory, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT group_id, name, keywords, created_at
+		FROM group_categories
+		WHERE group_id = $1
+		ORDER BY name
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*types.GroupCategory
+	for rows.Next() {
+		var c types.GroupCategory
+		if err := rows.Scan(&c.GroupID, &c.Name, pq.Array(&c.Keywords), &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &c)
+	}
+	return out, rows.Err()
+}
+
+func scanGroup(scanner interface{ Scan(dest ...any) error }) (*types.Group, error) {
+	var g types.Group
+	if err := scanner.Scan(&g.ID, &g.Name, &g.CreatedBy, &g.Version, &g.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// memberSnapshot is how a membership is recorded in the audit log
+type memberSnapshot struct {
+	GroupID string `json:"group_id"`
+	UserID  string `json:"user_id"`
+	Role    string `json:"role"`
 }
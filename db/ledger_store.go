@@ -0,0 +1,419 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+var ErrUnbalanced = errors.New("ledger: postings do not sum to zero")
+
+type LedgerStore interface {
+	// Balances reads the account totals with the group's balance version,
+	// both from the same snapshot. The version is bumped by every post; while
+	// it is still since the totals are skipped and the map is nil, so a
+	// cached copy is checked in the same round trip. since -1 always reads.
+	Balances(ctx context.Context, groupID string, since int64) (map[string]types.Money, int64, error)
+	// Recompute sums the group's postings from scratch, ignoring the account totals
+	Recompute(ctx context.Context, groupID string) (map[string]types.Money, error)
+	// Journals lists what was posted for one expense or settlement, oldest first
+	Journals(ctx context.Context, groupID, sourceType, sourceID string) ([]*types.Journal, error)
+	// Verify checks every journal balances, account totals match their
+	// postings, every group nets to zero and each live expense/settlement
+	// is reflected exactly, in one group or all of them when groupID is "".
+	// No issues means the ledger is consistent.
+	Verify(ctx context.Context, groupID string) ([]types.LedgerIssue, error)
+	// RebuildAccounts resets a group's account totals to the sum of its postings
+	RebuildAccounts(ctx context.Context, groupID string) error
+	// Resync posts whatever journal makes the ledger match the expense or
+	// settlement as it is now (a reversal if it no longer exists)
+	Resync(ctx context.Context, groupID, sourceType, sourceID string) error
+}
+
+type PostgresLedgerStore struct {
+	db *sql.DB
+}
+
+func NewPostgresLedgerStore(db *sql.DB) *PostgresLedgerStore {
+	return &PostgresLedgerStore{db: db}
+}
+
+func (s *PostgresLedgerStore) Balances(ctx context.Context, groupID string, since int64) (map[string]types.Money, int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT COALESCE(gb.version, 0), a.user_id, a.balance
+		FROM (SELECT $1::uuid AS group_id) g
+		LEFT JOIN group_balances gb ON gb.group_id = g.group_id
+		LEFT JOIN ledger_accounts a ON a.group_id = g.group_id AND COALESCE(gb.version, 0) <> $2
+	`, groupID, since)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var version int64
+	net := map[string]types.Money{}
+	for rows.Next() {
+		var (
+			userID  sql.NullString
+			balance sql.NullInt64
+		)
+		if err := rows.Scan(&version, &userID, &balance); err != nil {
+			return nil, 0, err
+		}
+		if userID.Valid {
+			net[userID.String] = types.Money(balance.Int64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if version == since {
+		return nil, version, nil
+	}
+	return net, version, nil
+}
+
+func (s *PostgresLedgerStore) Recompute(ctx context.Context, groupID string) (map[string]types.Money, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, SUM(amount)::bigint
+		FROM ledger_postings
+		WHERE group_id = $1
+		GROUP BY user_id
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	net := map[string]types.Money{}
+	for rows.Next() {
+		var userID string
+		var total types.Money
+		if err := rows.Scan(&userID, &total); err != nil {
+			return nil, err
+		}
+		net[userID] = total
+	}
+	return net, rows.Err()
+}
+
+func (s *PostgresLedgerStore) Journals(ctx context.Context, groupID, sourceType, sourceID string) ([]*types.Journal, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT j.id, j.group_id, j.source_type, j.source_id, j.memo, j.created_at, p.user_id, p.amount
+		FROM ledger_journals j
+		JOIN ledger_postings p ON p.journal_id = j.id
+		WHERE j.group_id = $1 AND j.source_type = $2 AND j.source_id = $3
+		ORDER BY j.created_at, j.id, p.amount DESC, p.user_id
+	`, groupID, sourceType, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*types.Journal
+	for rows.Next() {
+		var (
+			j    types.Journal
+			memo sql.NullString
+			p    types.Posting
+		)
+		if err := rows.Scan(&j.ID, &j.GroupID, &j.SourceType, &j.SourceID, &memo, &j.CreatedAt, &p.UserID, &p.Amount); err != nil {
+			return nil, err
+		}
+		if n := len(out); n == 0 || out[n-1].ID != j.ID {
+			j.Memo = memo.String
+			out = append(out, &j)
+		}
+		last := out[len(out)-1]
+		last.Postings = append(last.Postings, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresLedgerStore) Verify(ctx context.Context, groupID string) ([]types.LedgerIssue, error) {
+	issues := []types.LedgerIssue{}
+	// $1 is the group, or NULL for all of them
+	checks := []struct {
+		kind string
+		q    string
+	}{
+		{"unbalanced_journal", `
+			SELECT j.group_id, j.id, '', '', '', COALESCE(SUM(p.amount), 0)::bigint
+			FROM ledger_journals j
+			LEFT JOIN ledger_postings p ON p.journal_id = j.id
+			WHERE $1::uuid IS NULL OR j.group_id = $1
+			GROUP BY j.group_id, j.id
+			HAVING COALESCE(SUM(p.amount), 0) <> 0 OR COUNT(p.id) < 2`},
+		{"account_mismatch", `
+			SELECT COALESCE(a.group_id, p.group_id), '', COALESCE(a.user_id, p.user_id), '', '',
+				(COALESCE(a.balance, 0) - COALESCE(p.total, 0))::bigint
+			FROM (
+				SELECT * FROM ledger_accounts WHERE $1::uuid IS NULL OR group_id = $1
+			) a
+			FULL JOIN (
+				SELECT group_id, user_id, SUM(amount) AS total
+				FROM ledger_postings WHERE $1::uuid IS NULL OR group_id = $1
+				GROUP BY group_id, user_id
+			) p ON p.group_id = a.group_id AND p.user_id = a.user_id
+			WHERE COALESCE(a.balance, 0) <> COALESCE(p.total, 0)`},
+		{"unbalanced_group", `
+			SELECT group_id, '', '', '', '', SUM(balance)::bigint
+			FROM ledger_accounts
+			WHERE $1::uuid IS NULL OR group_id = $1
+			GROUP BY group_id
+			HAVING SUM(balance) <> 0`},
+		// what the ledger holds per source vs what the expense/settlement says now
+		{"source_mismatch", `
+			WITH want AS (
+				SELECT e.group_id, 'expense' AS source_type, e.id AS source_id, e.paid_by AS user_id, sp.exact AS amount
+				FROM expenses e JOIN expense_splits sp ON sp.expense_id = e.id
+				WHERE $1::uuid IS NULL OR e.group_id = $1
+				UNION ALL
+				SELECT e.group_id, 'expense', e.id, sp.user_id, -sp.exact
+				FROM expenses e JOIN expense_splits sp ON sp.expense_id = e.id
+				WHERE $1::uuid IS NULL OR e.group_id = $1
+				UNION ALL
+				SELECT group_id, 'settlement', id, from_user, amount FROM settlements
+				WHERE $1::uuid IS NULL OR group_id = $1
+				UNION ALL
+				SELECT group_id, 'settlement', id, to_user, -amount FROM settlements
+				WHERE $1::uuid IS NULL OR group_id = $1
+			), have AS (
+				SELECT j.group_id, j.source_type, j.source_id, p.user_id, p.amount
+				FROM ledger_journals j JOIN ledger_postings p ON p.journal_id = j.id
+				WHERE $1::uuid IS NULL OR j.group_id = $1
+			), diff AS (
+				SELECT group_id, source_type, source_id, user_id, SUM(amount) AS delta
+				FROM (
+					SELECT group_id, source_type, source_id, user_id, amount FROM want
+					UNION ALL
+					SELECT group_id, source_type, source_id, user_id, -amount FROM have
+				) x
+				GROUP BY group_id, source_type, source_id, user_id
+			)
+			SELECT group_id, '', user_id, source_type, source_id, delta::bigint
+			FROM diff WHERE delta <> 0`},
+	}
+
+	var group any
+	if groupID != "" {
+		group = groupID
+	}
+	for _, c := range checks {
+		rows, err := s.db.QueryContext(ctx, c.q, group)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.kind, translate(err, "group", groupID))
+		}
+		for rows.Next() {
+			var (
+				is  = types.LedgerIssue{Kind: c.kind}
+				off types.Money
+			)
+			if err := rows.Scan(&is.GroupID, &is.JournalID, &is.UserID, &is.SourceType, &is.SourceID, &off); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			is.Detail = fmt.Sprintf("off by %d paise", int64(off))
+			issues = append(issues, is)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return issues, nil
+}
+
+func (s *PostgresLedgerStore) RebuildAccounts(ctx context.Context, groupID string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		// lock the group's version row so no post interleaves with the rebuild
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO group_balances (group_id, version, updated_at)
+			VALUES ($1, 1, now())
+			ON CONFLICT (group_id) DO UPDATE SET version = group_balances.version + 1, updated_at = now()
+		`, groupID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM ledger_accounts WHERE group_id = $1`, groupID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_accounts (group_id, user_id, balance)
+			SELECT group_id, user_id, SUM(amount)
+			FROM ledger_postings
+			WHERE group_id = $1
+			GROUP BY group_id, user_id
+		`, groupID)
+		return err
+	})
+}
+
+func (s *PostgresLedgerStore) Resync(ctx context.Context, groupID, sourceType, sourceID string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var want map[string]types.Money
+		switch sourceType {
+		case "expense":
+			e, err := lockExpense(ctx, tx, groupID, sourceID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if e != nil {
+				if want, err = expenseLedger(e.PaidBy, e.Splits); err != nil {
+					return err
+				}
+			}
+		case "settlement":
+			st, err := scanSettlement(tx.QueryRowContext(ctx, `
+				SELECT id, group_id, from_user, to_user, amount, method, ref, created_at
+				FROM settlements
+				WHERE group_id = $1 AND id = $2
+				FOR UPDATE
+			`, groupID, sourceID))
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if st != nil {
+				want = settlementLedger(st)
+			}
+		default:
+			return fmt.Errorf("ledger: unknown source type %q", sourceType)
+		}
+		return syncLedger(ctx, tx, groupID, sourceType, sourceID, "resync", want)
+	})
+}
+
+// --- posting ---
+
+// syncLedger makes the ledger's net position for one expense/settlement
+// equal want (user => credit, summing to zero) by posting a single journal
+// with the difference from what is already there. Nothing is posted when
+// nothing changed; want == nil reverses the source entirely.
+func syncLedger(ctx context.Context, tx *sql.Tx, groupID, sourceType, sourceID, memo string, want map[string]types.Money) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT p.user_id, SUM(p.amount)::bigint
+		FROM ledger_journals j
+		JOIN ledger_postings p ON p.journal_id = j.id
+		WHERE j.source_type = $1 AND j.source_id = $2
+		GROUP BY p.user_id
+	`, sourceType, sourceID)
+	if err != nil {
+		return err
+	}
+	delta := map[string]types.Money{}
+	for rows.Next() {
+		var (
+			user string
+			have types.Money
+		)
+		if err := rows.Scan(&user, &have); err != nil {
+			rows.Close()
+			return err
+		}
+		delta[user] = -have
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	for user, amt := range want {
+		if delta[user], err = delta[user].Add(amt); err != nil {
+			return err
+		}
+	}
+
+	postings := make([]types.Posting, 0, len(delta))
+	for user, amt := range delta {
+		if amt != 0 {
+			postings = append(postings, types.Posting{UserID: user, Amount: amt})
+		}
+	}
+	if len(postings) == 0 {
+		return nil
+	}
+	sort.Slice(postings, func(i, j int) bool { return postings[i].UserID < postings[j].UserID })
+	return postJournal(ctx, tx, &types.Journal{
+		GroupID:    groupID,
+		SourceType: sourceType,
+		SourceID:   sourceID,
+		Memo:       memo,
+		Postings:   postings,
+	})
+}
+
+// postJournal writes a balanced journal and bumps the account totals.
+func postJournal(ctx context.Context, tx *sql.Tx, j *types.Journal) error {
+	amounts := make([]types.Money, len(j.Postings))
+	for i, p := range j.Postings {
+		amounts[i] = p.Amount
+	}
+	if sum, err := types.Sum(amounts...); err != nil || sum != 0 {
+		return ErrUnbalanced
+	}
+
+	j.ID, j.CreatedAt = uuid.New().String(), time.Now()
+	// bumping the version first also locks the group's row, so posts (and
+	// RebuildAccounts) for one group are serialized
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO group_balances (group_id, version, updated_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (group_id) DO UPDATE SET version = group_balances.version + 1, updated_at = EXCLUDED.updated_at
+	`, j.GroupID, j.CreatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO ledger_journals (id, group_id, source_type, source_id, memo, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`, j.ID, j.GroupID, j.SourceType, j.SourceID, nullIfEmpty(j.Memo), j.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for _, p := range j.Postings {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_postings (id, journal_id, group_id, user_id, amount)
+			VALUES ($1,$2,$3,$4,$5)
+		`, uuid.New().String(), j.ID, j.GroupID, p.UserID, p.Amount)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO ledger_accounts (group_id, user_id, balance)
+			VALUES ($1,$2,$3)
+			ON CONFLICT (group_id, user_id) DO UPDATE SET balance = ledger_accounts.balance + EXCLUDED.balance
+		`, j.GroupID, p.UserID, p.Amount)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expenseLedger is what an expense should hold in the ledger: the payer is
+// credited every share, each participant debited their own.
+func expenseLedger(paidBy string, splits []types.ExpenseSplit) (map[string]types.Money, error) {
+	want := map[string]types.Money{}
+	var err error
+	for _, sp := range splits {
+		if want[paidBy], err = want[paidBy].Add(sp.Exact); err != nil {
+			return nil, err
+		}
+		if want[sp.UserID], err = want[sp.UserID].Sub(sp.Exact); err != nil {
+			return nil, err
+		}
+	}
+	return want, nil
+}
+
+// settlementLedger: paying someone back works like paying their share.
+func settlementLedger(st *types.Settlement) map[string]types.Money {
+	return map[string]types.Money{st.FromUser: st.Amount, st.ToUser: -st.Amount}
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+type MemoryExpenseStore struct {
+	m *MemoryDB
+}
+
+func NewMemoryExpenseStore(m *MemoryDB) *MemoryExpenseStore {
+	return &MemoryExpenseStore{m: m}
+}
+
+func (s *MemoryExpenseStore) Create(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) (string, error) {
+	want, err := expenseLedger(e.PaidBy, splits)
+	if err != nil {
+		return "", err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.groups[e.GroupID]; !ok {
+		return "", fkError("group_id")
+	}
+	if err := s.checkUsers(e.PaidBy, splits); err != nil {
+		return "", err
+	}
+
+	e.ID, e.Version, e.CreatedAt = uuid.New().String(), 1, time.Now()
+	stored := *e
+	s.m.expenses[e.ID] = &stored
+	s.m.splits[e.ID] = newSplits(e.ID, splits)
+	s.m.post(e.GroupID, e.ID, want)
+	return e.ID, nil
+}
+
+func (s *MemoryExpenseStore) ListByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.Expense, string, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	page, limit, err := f.pageMemory(s.m, groupID, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	var out []*types.Expense
+	for _, e := range page {
+		cp := *e
+		out = append(out, &cp)
+	}
+	next := ""
+	if len(out) > limit {
+		out = out[:limit]
+		next = EncodeCursor(out[limit-1].CreatedAt, out[limit-1].ID)
+	}
+	return out, next, nil
+}
+
+func (s *MemoryExpenseStore) ListDetailsByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.ExpenseDetail, string, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	page, limit, err := f.pageMemory(s.m, groupID, limit, cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	var out []*types.ExpenseDetail
+	for _, e := range page {
+		out = append(out, s.detail(e))
+	}
+	next := ""
+	if len(out) > limit {
+		out = out[:limit]
+		next = EncodeCursor(out[limit-1].CreatedAt, out[limit-1].ID)
+	}
+	return out, next, nil
+}
+
+func (s *MemoryExpenseStore) Get(ctx context.Context, groupID, id string) (*types.ExpenseDetail, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	e, ok := s.m.expenses[id]
+	if !ok || e.GroupID != groupID {
+		return nil, NotFound("expense", id)
+	}
+	return s.detail(e), nil
+}
+
+func (s *MemoryExpenseStore) Update(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) error {
+	want, err := expenseLedger(e.PaidBy, splits)
+	if err != nil {
+		return err
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	cur, ok := s.m.expenses[e.ID]
+	if !ok || cur.GroupID != e.GroupID {
+		return NotFound("expense", e.ID)
+	}
+	if cur.Version != e.Version {
+		return ErrVersionConflict
+	}
+	if err := s.checkUsers(e.PaidBy, splits); err != nil {
+		return err
+	}
+
+	// currency and created_at aren't editable
+	cur.PaidBy, cur.AmountPaise, cur.Note = e.PaidBy, e.AmountPaise, e.Note
+	cur.Category, cur.MerchantVPA, cur.SplitKind = e.Category, e.MerchantVPA, e.SplitKind
+	cur.Version++
+	s.m.splits[e.ID] = newSplits(e.ID, splits)
+	s.m.post(e.GroupID, e.ID, want)
+	e.Version = cur.Version
+	return nil
+}
+
+func (s *MemoryExpenseStore) Delete(ctx context.Context, groupID, id string, version int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	cur, ok := s.m.expenses[id]
+	if !ok || cur.GroupID != groupID {
+		return NotFound("expense", id)
+	}
+	if cur.Version != version {
+		return ErrVersionConflict
+	}
+	delete(s.m.expenses, id)
+	delete(s.m.splits, id)
+	s.m.post(groupID, id, nil)
+	return nil
+}
+
+// Balances reads the ledger accounts; positive means the group owes them.
+// Only expenses post here, there is no memory settlement store.
+func (s *MemoryExpenseStore) Balances(ctx context.Context, groupID string) (map[string]types.Money, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	net := map[string]types.Money{}
+	for u, bal := range s.m.accounts[groupID] {
+		net[u] = bal
+	}
+	return net, nil
+}
+
+func (s *MemoryExpenseStore) TotalsByCategory(ctx context.Context, groupID string, f ExpenseFilter) ([]types.CategoryTotal, error) {
+	s.m.mu.RLock()
+	byCat := map[types.Category]*types.CategoryTotal{}
+	for _, e := range s.m.expenses {
+		if e.GroupID != groupID || !f.matches(s.m, e) {
+			continue
+		}
+		t := byCat[e.Category]
+		if t == nil {
+			t = &types.CategoryTotal{Category: e.Category}
+			byCat[e.Category] = t
+		}
+		t.Count++
+		t.Total += e.AmountPaise
+	}
+	s.m.mu.RUnlock()
+
+	out := []types.CategoryTotal{}
+	for _, t := range byCat {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Category < out[j].Category
+	})
+	return out, nil
+}
+
+// checkUsers is the foreign key check on paid_by and split users. Callers
+// hold the lock.
+func (s *MemoryExpenseStore) checkUsers(paidBy string, splits []types.ExpenseSplit) error {
+	if _, ok := s.m.users[paidBy]; !ok {
+		return fkError("paid_by")
+	}
+	for _, sp := range splits {
+		if _, ok := s.m.users[sp.UserID]; !ok {
+			return fkError("user_id")
+		}
+	}
+	return nil
+}
+
+// detail copies e with payer and participant names, splits ordered like
+// the Postgres query (biggest share first). Callers hold the lock.
+func (s *MemoryExpenseStore) detail(e *types.Expense) *types.ExpenseDetail {
+	name := func(id string) string {
+		if u, ok := s.m.users[id]; ok {
+			return u.Name
+		}
+		return ""
+	}
+	d := &types.ExpenseDetail{Expense: *e, PaidByName: name(e.PaidBy), Splits: []types.ExpenseSplitDetail{}}
+	for _, sp := range s.m.splits[e.ID] {
+		d.Splits = append(d.Splits, types.ExpenseSplitDetail{ExpenseSplit: sp, UserName: name(sp.UserID)})
+	}
+	sort.Slice(d.Splits, func(i, j int) bool {
+		if d.Splits[i].Exact != d.Splits[j].Exact {
+			return d.Splits[i].Exact > d.Splits[j].Exact
+		}
+		return d.Splits[i].UserID < d.Splits[j].UserID
+	})
+	return d
+}
+
+func newSplits(expenseID string, splits []types.ExpenseSplit) []types.ExpenseSplit {
+	out := make([]types.ExpenseSplit, len(splits))
+	for i, sp := range splits {
+		out[i] = types.ExpenseSplit{ID: uuid.New().String(), ExpenseID: expenseID, UserID: sp.UserID, Exact: sp.Exact}
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+type MemoryGroupStore struct {
+	m *MemoryDB
+}
+
+func NewMemoryGroupStore(m *MemoryDB) *MemoryGroupStore {
+	return &MemoryGroupStore{m: m}
+}
+
+func (s *MemoryGroupStore) Create(ctx context.Context, g *types.Group) (string, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.users[g.CreatedBy]; !ok {
+		return "", fkError("created_by")
+	}
+	now := time.Now()
+	g.ID, g.Version, g.CreatedAt = uuid.New().String(), 1, now
+	stored := *g
+	s.m.groups[g.ID] = &stored
+	// also insert creator as member (admin)
+	s.m.members[g.ID] = []memoryMember{{userID: g.CreatedBy, role: "admin", addedAt: now}}
+	return g.ID, nil
+}
+
+func (s *MemoryGroupStore) Get(ctx context.Context, id string) (*types.Group, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	g, ok := s.m.groups[id]
+	if !ok {
+		return nil, NotFound("group", id)
+	}
+	out := *g
+	return &out, nil
+}
+
+func (s *MemoryGroupStore) Update(ctx context.Context, g *types.Group) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	cur, ok := s.m.groups[g.ID]
+	if !ok {
+		return NotFound("group", g.ID)
+	}
+	if cur.Version != g.Version {
+		return ErrVersionConflict
+	}
+	cur.Name = g.Name
+	cur.Version++
+	*g = *cur
+	return nil
+}
+
+func (s *MemoryGroupStore) ListByUser(ctx context.Context, userID string) ([]*types.Group, error) {
+	s.m.mu.RLock()
+	var out []*types.Group
+	for gid, g := range s.m.groups {
+		if s.m.isMember(gid, userID) {
+			cp := *g
+			out = append(out, &cp)
+		}
+	}
+	s.m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+// AddMember is a no-op for an existing member (ON CONFLICT DO NOTHING)
+func (s *MemoryGroupStore) AddMember(ctx context.Context, groupID, userID string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.groups[groupID]; !ok {
+		return fkError("group_id")
+	}
+	if _, ok := s.m.users[userID]; !ok {
+		return fkError("user_id")
+	}
+	if s.m.isMember(groupID, userID) {
+		return nil
+	}
+	s.m.members[groupID] = append(s.m.members[groupID], memoryMember{userID: userID, role: "member", addedAt: time.Now()})
+	return nil
+}
+
+func (s *MemoryGroupStore) ListMembers(ctx context.Context, groupID string) ([]string, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	var out []string
+	for _, mb := range s.m.members[groupID] {
+		out = append(out, mb.userID)
+	}
+	return out, nil
+}
+
+// AddCategory upserts by name, keeping the original created_at
+func (s *MemoryGroupStore) AddCategory(ctx context.Context, c *types.GroupCategory) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if _, ok := s.m.groups[c.GroupID]; !ok {
+		return fkError("group_id")
+	}
+	byName := s.m.categories[c.GroupID]
+	if byName == nil {
+		byName = map[types.Category]*types.GroupCategory{}
+		s.m.categories[c.GroupID] = byName
+	}
+	c.CreatedAt = time.Now()
+	if prev, ok := byName[c.Name]; ok {
+		c.CreatedAt = prev.CreatedAt
+	}
+	stored := *c
+	stored.Keywords = append([]string{}, c.Keywords...)
+	byName[c.Name] = &stored
+	return nil
+}
+
+func (s *MemoryGroupStore) ListCategories(ctx context.Context, groupID string) ([]*types.GroupCategory, error) {
+	s.m.mu.RLock()
+	var out []*types.GroupCategory
+	for _, c := range s.m.categories[groupID] {
+		cp := *c
+		cp.Keywords = append([]string{}, c.Keywords...)
+		out = append(out, &cp)
+	}
+	s.m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+// MemoryDB is the in-memory stand-in for Postgres that the Memory*Store
+// types share, the way the Postgres stores share a *sql.DB. It keeps the
+// same rules the schema enforces (unique email/phone, foreign keys, ON
+// CONFLICT DO NOTHING membership) and the same ledger-backed balances, but
+// has no audit log. Meant for tests and local experiments.
+type MemoryDB struct {
+	mu         sync.RWMutex
+	users      map[string]*types.User
+	groups     map[string]*types.Group
+	members    map[string][]memoryMember // by group, in the order they joined
+	categories map[string]map[types.Category]*types.GroupCategory
+	expenses   map[string]*types.Expense
+	splits     map[string][]types.ExpenseSplit   // by expense
+	positions  map[string]map[string]types.Money // what each expense has posted, by user
+	accounts   map[string]map[string]types.Money // ledger_accounts: group -> user -> balance
+}
+
+type memoryMember struct {
+	userID  string
+	role    string
+	addedAt time.Time
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		users:      map[string]*types.User{},
+		groups:     map[string]*types.Group{},
+		members:    map[string][]memoryMember{},
+		categories: map[string]map[types.Category]*types.GroupCategory{},
+		expenses:   map[string]*types.Expense{},
+		splits:     map[string][]types.ExpenseSplit{},
+		positions:  map[string]map[string]types.Money{},
+		accounts:   map[string]map[string]types.Money{},
+	}
+}
+
+// fkError is what translate makes of a foreign key violation in Postgres.
+func fkError(column string) error {
+	return Invalid(column, "does not exist")
+}
+
+// post moves the group's accounts from what sourceID had posted to want,
+// like syncLedger does for the Postgres stores.
+func (m *MemoryDB) post(groupID, sourceID string, want map[string]types.Money) {
+	acct := m.accounts[groupID]
+	if acct == nil {
+		acct = map[string]types.Money{}
+		m.accounts[groupID] = acct
+	}
+	for u, amt := range m.positions[sourceID] {
+		acct[u] -= amt
+	}
+	pos := map[string]types.Money{}
+	for u, amt := range want {
+		if amt != 0 {
+			acct[u] += amt
+			pos[u] = amt
+		}
+	}
+	if len(pos) == 0 {
+		delete(m.positions, sourceID)
+		return
+	}
+	m.positions[sourceID] = pos
+}
+
+func (m *MemoryDB) isMember(groupID, userID string) bool {
+	for _, mb := range m.members[groupID] {
+		if mb.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// matches is the memory version of ExpenseFilter.where.
+func (f ExpenseFilter) matches(m *MemoryDB, e *types.Expense) bool {
+	switch {
+	case f.From != nil && e.CreatedAt.Before(*f.From),
+		f.To != nil && !e.CreatedAt.Before(*f.To),
+		f.PaidBy != nil && e.PaidBy != *f.PaidBy,
+		f.Category != nil && e.Category != *f.Category,
+		f.MinAmount != nil && e.AmountPaise < *f.MinAmount,
+		f.MaxAmount != nil && e.AmountPaise > *f.MaxAmount:
+		return false
+	}
+	if f.Participant != nil {
+		found := false
+		for _, sp := range m.splits[e.ID] {
+			if sp.UserID == *f.Participant {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Search != nil && strings.TrimSpace(*f.Search) != "" {
+		// plainto_tsquery('simple', ...): every word has to be in the note
+		words := map[string]bool{}
+		for _, w := range tsWords(e.Note) {
+			words[w] = true
+		}
+		for _, w := range tsWords(*f.Search) {
+			if !words[w] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func tsWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// pageMemory is the memory version of ExpenseFilter.page: matching expenses,
+// newest first, after the cursor, at most limit+1 of them.
+func (f ExpenseFilter) pageMemory(m *MemoryDB, groupID string, limit int, cursor string) ([]*types.Expense, int, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var afterAt time.Time
+	var afterID string
+	if cursor != "" {
+		var err error
+		if afterAt, afterID, err = DecodeCursor(cursor); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var out []*types.Expense
+	for _, e := range m.expenses {
+		if e.GroupID != groupID || !f.matches(m, e) {
+			continue
+		}
+		if cursor != "" && !newerFirst(afterAt, afterID, e.CreatedAt, e.ID) {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return newerFirst(out[i].CreatedAt, out[i].ID, out[j].CreatedAt, out[j].ID)
+	})
+	if len(out) > limit+1 {
+		out = out[:limit+1]
+	}
+	return out, limit, nil
+}
+
+// newerFirst reports whether (at1, id1) sorts before (at2, id2) in
+// ORDER BY created_at DESC, id DESC.
+func newerFirst(at1 time.Time, id1 string, at2 time.Time, id2 string) bool {
+	if !at1.Equal(at2) {
+		return at1.After(at2)
+	}
+	return id1 > id2
+}
+
+var (
+	_ UserStore    = (*MemoryUserStore)(nil)
+	_ GroupStore   = (*MemoryGroupStore)(nil)
+	_ ExpenseStore = (*MemoryExpenseStore)(nil)
+)
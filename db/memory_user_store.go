@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+type MemoryUserStore struct {
+	m *MemoryDB
+}
+
+func NewMemoryUserStore(m *MemoryDB) *MemoryUserStore {
+	return &MemoryUserStore{m: m}
+}
+
+func (s *MemoryUserStore) Create(ctx context.Context, u *types.User) error {
+	if strings.TrimSpace(u.Name) == "" {
+		return Invalid("name", "required")
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	if err := s.unique(u, ""); err != nil {
+		return err
+	}
+	u.ID, u.Version, u.CreatedAt = uuid.New().String(), 1, time.Now()
+	s.m.users[u.ID] = copyUser(u)
+	return nil
+}
+
+func (s *MemoryUserStore) GetByID(ctx context.Context, id string) (*types.User, error) {
+	return s.first(id, func(u *types.User) bool { return u.ID == id })
+}
+
+func (s *MemoryUserStore) GetByEmail(ctx context.Context, email string) (*types.User, error) {
+	return s.first("", func(u *types.User) bool { return u.Email != nil && *u.Email == email })
+}
+
+func (s *MemoryUserStore) GetByPhone(ctx context.Context, phone string) (*types.User, error) {
+	return s.first("", func(u *types.User) bool { return u.Phone != nil && *u.Phone == phone })
+}
+
+func (s *MemoryUserStore) GetByUPI(ctx context.Context, vpa string) (*types.User, error) {
+	vpa = strings.TrimSpace(vpa)
+	return s.first("", func(u *types.User) bool { return u.UPI != nil && strings.EqualFold(*u.UPI, vpa) })
+}
+
+func (s *MemoryUserStore) Find(ctx context.Context, f UserFilter, limit, offset int) ([]*types.User, error) {
+	q := ""
+	if f.Query != nil {
+		q = strings.ToLower(strings.TrimSpace(*f.Query))
+	}
+	// ILIKE '%q%' on name, email or phone
+	contains := func(s *string) bool { return s != nil && strings.Contains(strings.ToLower(*s), q) }
+
+	s.m.mu.RLock()
+	var out []*types.User
+	for _, u := range s.m.users {
+		if f.Email != nil && (u.Email == nil || *u.Email != *f.Email) {
+			continue
+		}
+		if f.Phone != nil && (u.Phone == nil || *u.Phone != *f.Phone) {
+			continue
+		}
+		if q != "" && !contains(&u.Name) && !contains(u.Email) && !contains(u.Phone) {
+			continue
+		}
+		out = append(out, copyUser(u))
+	}
+	s.m.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	if limit <= 0 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	out = out[offset:]
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *MemoryUserStore) Update(ctx context.Context, u *types.User) error {
+	if strings.TrimSpace(u.ID) == "" {
+		return Invalid("id", "required")
+	}
+	if strings.TrimSpace(u.Name) == "" {
+		return Invalid("name", "required")
+	}
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	cur, ok := s.m.users[u.ID]
+	if !ok {
+		return NotFound("user", u.ID)
+	}
+	if cur.Version != u.Version {
+		return ErrVersionConflict
+	}
+	if err := s.unique(u, u.ID); err != nil {
+		return err
+	}
+	u.Version++
+	stored := copyUser(u)
+	stored.CreatedAt = cur.CreatedAt
+	s.m.users[u.ID] = stored
+	return nil
+}
+
+func (s *MemoryUserStore) UpsertUPI(ctx context.Context, userID, vpa string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.users[userID]
+	if !ok {
+		return NotFound("user", userID)
+	}
+	u.UPI = &vpa
+	u.Version++
+	return nil
+}
+
+func (s *MemoryUserStore) Delete(ctx context.Context, id string, version int64) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+	u, ok := s.m.users[id]
+	if !ok {
+		return NotFound("user", id)
+	}
+	if u.Version != version {
+		return ErrVersionConflict
+	}
+	// groups, expenses and splits keep their users; memberships cascade
+	for _, g := range s.m.groups {
+		if g.CreatedBy == id {
+			return inUse("groups")
+		}
+	}
+	for eid, e := range s.m.expenses {
+		if e.PaidBy == id {
+			return inUse("expenses")
+		}
+		for _, sp := range s.m.splits[eid] {
+			if sp.UserID == id {
+				return inUse("expense_splits")
+			}
+		}
+	}
+	for gid, ms := range s.m.members {
+		kept := ms[:0]
+		for _, mb := range ms {
+			if mb.userID != id {
+				kept = append(kept, mb)
+			}
+		}
+		s.m.members[gid] = kept
+	}
+	delete(s.m.users, id)
+	return nil
+}
+
+func (s *MemoryUserStore) first(id string, match func(u *types.User) bool) (*types.User, error) {
+	s.m.mu.RLock()
+	defer s.m.mu.RUnlock()
+	for _, u := range s.m.users {
+		if match(u) {
+			return copyUser(u), nil
+		}
+	}
+	return nil, NotFound("user", id)
+}
+
+// unique enforces the UNIQUE constraints on email and phone; self is the
+// user being updated. Callers hold the lock.
+func (s *MemoryUserStore) unique(u *types.User, self string) error {
+	for _, other := range s.m.users {
+		if other.ID == self {
+			continue
+		}
+		if u.Email != nil && other.Email != nil && *u.Email == *other.Email {
+			return &ConflictError{Code: "already_exists", Message: "email already in use"}
+		}
+		if u.Phone != nil && other.Phone != nil && *u.Phone == *other.Phone {
+			return &ConflictError{Code: "already_exists", Message: "phone already in use"}
+		}
+	}
+	return nil
+}
+
+func inUse(table string) error {
+	return &ConflictError{Code: "in_use", Message: "user is still referenced by " + table}
+}
+
+func copyUser(u *types.User) *types.User {
+	c := *u
+	for _, p := range []**string{&c.Email, &c.Phone, &c.UPI} {
+		if *p != nil {
+			v := **p
+			*p = &v
+		}
+	}
+	return &c
+}
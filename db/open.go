@@ -0,0 +1,21 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/akarshgo/paysplit/config"
+)
+
+// Open returns a Postgres pool sized by cfg. Like sql.Open it doesn't
+// connect; the first query (or a Ping) does.
+func Open(cfg config.Database) (*sql.DB, error) {
+	sqlDB, err := sql.Open("postgres", cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return sqlDB, nil
+}
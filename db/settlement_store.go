@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+type SettlementStore interface {
+	Create(ctx context.Context, s *types.Settlement) (string, error)
+	// CreateBatch inserts all settlements or none of them
+	CreateBatch(ctx context.Context, in []*types.Settlement) ([]string, error)
+	ListByGroup(ctx context.Context, groupID string) ([]*types.Settlement, error)
+	Get(ctx context.Context, groupID, id string) (*types.Settlement, error)
+}
+
+type PostgresSettlementStore struct {
+	db *sql.DB
+}
+
+func NewPostgresSettlementStore(db *sql.DB) *PostgresSettlementStore {
+	return &PostgresSettlementStore{db: db}
+}
+
+func (s *PostgresSettlementStore) Create(ctx context.Context, st *types.Settlement) (string, error) {
+	ids, err := s.CreateBatch(ctx, []*types.Settlement{st})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+func (s *PostgresSettlementStore) CreateBatch(ctx context.Context, in []*types.Settlement) ([]string, error) {
+	ids, err := s.createBatch(ctx, in)
+	return ids, translate(err, "settlement", "")
+}
+
+func (s *PostgresSettlementStore) createBatch(ctx context.Context, in []*types.Settlement) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	now := time.Now()
+	ids := make([]string, 0, len(in))
+	for _, st := range in {
+		id := uuid.New().String()
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO settlements (id, group_id, from_user, to_user, amount, method, ref, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		`, id, st.GroupID, st.FromUser, st.ToUser, st.Amount, st.Method, nullIfEmpty(st.Ref), now)
+		if err != nil {
+			return nil, err
+		}
+		if err = syncLedger(ctx, tx, st.GroupID, "settlement", id, "settlement", settlementLedger(st)); err != nil {
+			return nil, err
+		}
+		after := *st
+		after.ID, after.CreatedAt = id, now
+		if err = audit(ctx, tx, st.GroupID, "settlement", id, "create", nil, after); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for i, st := range in {
+		st.ID, st.CreatedAt = ids[i], now
+	}
+	return ids, nil
+}
+
+func (s *PostgresSettlementStore) ListByGroup(ctx context.Context, groupID string) ([]*types.Settlement, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, group_id, from_user, to_user, amount, method, ref, created_at
+		FROM settlements
+		WHERE group_id = $1
+		ORDER BY created_at DESC
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*types.Settlement
+	for rows.Next() {
+		st, err := scanSettlement(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+func (s *PostgresSettlementStore) Get(ctx context.Context, groupID, id string) (*types.Settlement, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, group_id, from_user, to_user, amount, method, ref, created_at
+		FROM settlements
+		WHERE group_id = $1 AND id = $2
+	`, groupID, id)
+	st, err := scanSettlement(row)
+	return st, translate(err, "settlement", id)
+}
+
+func scanSettlement(scanner interface{ Scan(dest ...any) error }) (*types.Settlement, error) {
+	var (
+		st    types.Settlement
+		refNS sql.NullString
+	)
+	if err := scanner.Scan(&st.ID, &st.GroupID, &st.FromUser, &st.ToUser, &st.Amount, &st.Method, &refNS, &st.CreatedAt); err != nil {
+		return nil, err
+	}
+	st.Ref = refNS.String
+	return &st, nil
+}
@@ -0,0 +1,336 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/akarshgo/paysplit/db"
+	"github.com/akarshgo/paysplit/migrate"
+	"github.com/akarshgo/paysplit/types"
+	"github.com/google/uuid"
+)
+
+// The memory stores stand in for Postgres in handler tests, so both have to
+// behave the same. storeContract is that behaviour; it runs against each
+// implementation through a factory.
+
+type stores struct {
+	users    db.UserStore
+	groups   db.GroupStore
+	expenses db.ExpenseStore
+}
+
+func TestMemoryStores(t *testing.T) {
+	storeContract(t, func(t *testing.T) stores {
+		m := db.NewMemoryDB()
+		return stores{db.NewMemoryUserStore(m), db.NewMemoryGroupStore(m), db.NewMemoryExpenseStore(m)}
+	})
+}
+
+// TestPostgresStores needs DATABASE_URL; it migrates a scratch database
+// created next to that one and drops it afterwards.
+func TestPostgresStores(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		t.Skip("DATABASE_URL must be a postgres:// URL to create a scratch database")
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	name := fmt.Sprintf("paysplit_store_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE DATABASE ` + name); err != nil {
+		t.Fatalf("create database: %v", err)
+	}
+	defer func() {
+		if _, err := admin.Exec(`DROP DATABASE IF EXISTS ` + name); err != nil {
+			t.Errorf("drop database %s: %v", name, err)
+		}
+	}()
+
+	u.Path = "/" + name
+	sqlDB, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	m, err := migrate.New(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// subtests share the database but only look at rows they created
+	storeContract(t, func(t *testing.T) stores {
+		return stores{db.NewPostgresUserStore(sqlDB), db.NewPostgresGroupStore(sqlDB), db.NewPostgresExpenseStore(sqlDB)}
+	})
+}
+
+func storeContract(t *testing.T, newStores func(t *testing.T) stores) {
+	ctx := context.Background()
+
+	// newUsers creates users named after names, returning their IDs in order.
+	newUsers := func(t *testing.T, s stores, names ...string) []string {
+		t.Helper()
+		ids := make([]string, len(names))
+		for i, n := range names {
+			u := &types.User{Name: n}
+			if err := s.users.Create(ctx, u); err != nil {
+				t.Fatalf("create user %s: %v", n, err)
+			}
+			ids[i] = u.ID
+		}
+		return ids
+	}
+	newGroup := func(t *testing.T, s stores, createdBy string) string {
+		t.Helper()
+		id, err := s.groups.Create(ctx, &types.Group{Name: "trip", CreatedBy: createdBy})
+		if err != nil {
+			t.Fatalf("create group: %v", err)
+		}
+		return id
+	}
+	newExpense := func(t *testing.T, s stores, groupID, paidBy string, splits ...types.ExpenseSplit) *types.Expense {
+		t.Helper()
+		e := &types.Expense{GroupID: groupID, PaidBy: paidBy, Currency: "INR", Category: types.CategoryFood, SplitKind: types.SplitExact}
+		for _, sp := range splits {
+			e.AmountPaise += sp.Exact
+		}
+		if _, err := s.expenses.Create(ctx, e, splits); err != nil {
+			t.Fatalf("create expense: %v", err)
+		}
+		return e
+	}
+	split := func(userID string, paise types.Money) types.ExpenseSplit {
+		return types.ExpenseSplit{UserID: userID, Exact: paise}
+	}
+	isInvalid := func(err error) bool {
+		var ve *db.ValidationError
+		return errors.As(err, &ve)
+	}
+
+	t.Run("AddMember does nothing for an existing member", func(t *testing.T) {
+		s := newStores(t)
+		ids := newUsers(t, s, "asha", "bala")
+		g := newGroup(t, s, ids[0])
+
+		for range 2 {
+			if err := s.groups.AddMember(ctx, g, ids[1]); err != nil {
+				t.Fatalf("add member: %v", err)
+			}
+		}
+		// the creator is a member already
+		if err := s.groups.AddMember(ctx, g, ids[0]); err != nil {
+			t.Fatalf("add creator: %v", err)
+		}
+		members, err := s.groups.ListMembers(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(members) != fmt.Sprint(ids) {
+			t.Errorf("members = %v, want %v in join order", members, ids)
+		}
+
+		if err := s.groups.AddMember(ctx, uuid.New().String(), ids[1]); !isInvalid(err) {
+			t.Errorf("add to missing group = %v, want a validation error", err)
+		}
+		if err := s.groups.AddMember(ctx, g, uuid.New().String()); !isInvalid(err) {
+			t.Errorf("add missing user = %v, want a validation error", err)
+		}
+	})
+
+	t.Run("expenses page newest first", func(t *testing.T) {
+		s := newStores(t)
+		ids := newUsers(t, s, "asha", "bala")
+		g := newGroup(t, s, ids[0])
+		created := map[string]bool{}
+		for i := range 5 {
+			e := newExpense(t, s, g, ids[0], split(ids[1], types.Money(100*(i+1))))
+			created[e.ID] = true
+		}
+		// another group's expenses stay out of the list
+		newExpense(t, s, newGroup(t, s, ids[1]), ids[1], split(ids[0], 100))
+
+		var got []*types.Expense
+		cursor, pages := "", 0
+		for {
+			page, next, err := s.expenses.ListByGroup(ctx, g, db.ExpenseFilter{}, 2, cursor)
+			if err != nil {
+				t.Fatalf("page %d: %v", pages, err)
+			}
+			got = append(got, page...)
+			pages++
+			if next == "" {
+				break
+			}
+			if pages > 5 {
+				t.Fatal("cursor never ran out")
+			}
+			cursor = next
+		}
+		if pages != 3 || len(got) != 5 {
+			t.Fatalf("got %d expenses in %d pages, want 5 in 3", len(got), pages)
+		}
+		sorted := sort.SliceIsSorted(got, func(i, j int) bool {
+			if !got[i].CreatedAt.Equal(got[j].CreatedAt) {
+				return got[i].CreatedAt.After(got[j].CreatedAt)
+			}
+			return got[i].ID > got[j].ID
+		})
+		if !sorted {
+			t.Error("expenses not ordered by created_at DESC, id DESC")
+		}
+		for _, e := range got {
+			if !created[e.ID] {
+				t.Errorf("unexpected or repeated expense %s", e.ID)
+			}
+			delete(created, e.ID)
+		}
+
+		details, next, err := s.expenses.ListDetailsByGroup(ctx, g, db.ExpenseFilter{}, 10, "")
+		if err != nil || next != "" || len(details) != 5 {
+			t.Fatalf("details = %d, next %q, %v; want all 5 on one page", len(details), next, err)
+		}
+		for i, d := range details {
+			if d.ID != got[i].ID {
+				t.Errorf("details[%d] = %s, want %s like ListByGroup", i, d.ID, got[i].ID)
+			}
+		}
+
+		if _, _, err := s.expenses.ListByGroup(ctx, g, db.ExpenseFilter{}, 2, "not a cursor"); !errors.Is(err, db.ErrBadCursor) {
+			t.Errorf("bad cursor = %v, want ErrBadCursor", err)
+		}
+	})
+
+	t.Run("Balances follow creates, updates and deletes", func(t *testing.T) {
+		s := newStores(t)
+		ids := newUsers(t, s, "asha", "bala", "chitra")
+		a, b, c := ids[0], ids[1], ids[2]
+		g := newGroup(t, s, a)
+
+		e := newExpense(t, s, g, a, split(a, 100), split(b, 100), split(c, 100))
+		wantBalances(t, s, g, map[string]types.Money{a: 200, b: -100, c: -100})
+
+		e.PaidBy = b
+		if err := s.expenses.Update(ctx, e, []types.ExpenseSplit{split(a, 150), split(b, 150)}); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		wantBalances(t, s, g, map[string]types.Money{a: -150, b: 150})
+
+		newExpense(t, s, g, c, split(a, 50))
+		wantBalances(t, s, g, map[string]types.Money{a: -200, b: 150, c: 50})
+
+		if err := s.expenses.Delete(ctx, g, e.ID, e.Version); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		wantBalances(t, s, g, map[string]types.Money{a: -50, c: 50})
+	})
+
+	t.Run("stale versions conflict and missing rows are NotFound", func(t *testing.T) {
+		s := newStores(t)
+		ids := newUsers(t, s, "asha", "bala")
+		g := newGroup(t, s, ids[0])
+		e := newExpense(t, s, g, ids[0], split(ids[1], 100))
+		if e.Version != 1 {
+			t.Fatalf("new expense version = %d, want 1", e.Version)
+		}
+
+		u, err := s.users.GetByID(ctx, ids[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.Name = "asha k"
+		if err := s.users.Update(ctx, u); err != nil || u.Version != 2 {
+			t.Fatalf("update user = %v, version %d; want version 2", err, u.Version)
+		}
+		u.Version = 1
+		if err := s.users.Update(ctx, u); !errors.Is(err, db.ErrVersionConflict) {
+			t.Errorf("stale user update = %v, want ErrVersionConflict", err)
+		}
+		if err := s.users.Delete(ctx, ids[1], 7); !errors.Is(err, db.ErrVersionConflict) {
+			t.Errorf("stale user delete = %v, want ErrVersionConflict", err)
+		}
+
+		grp, err := s.groups.Get(ctx, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		grp.Name, grp.Version = "goa", grp.Version+1
+		if err := s.groups.Update(ctx, grp); !errors.Is(err, db.ErrVersionConflict) {
+			t.Errorf("stale group update = %v, want ErrVersionConflict", err)
+		}
+
+		e.Note = "dinner"
+		if err := s.expenses.Update(ctx, e, []types.ExpenseSplit{split(ids[1], 100)}); err != nil || e.Version != 2 {
+			t.Fatalf("update expense = %v, version %d; want version 2", err, e.Version)
+		}
+		if err := s.expenses.Update(ctx, &types.Expense{ID: e.ID, GroupID: g, PaidBy: ids[0], AmountPaise: 100, Version: 1},
+			[]types.ExpenseSplit{split(ids[1], 100)}); !errors.Is(err, db.ErrVersionConflict) {
+			t.Errorf("stale expense update = %v, want ErrVersionConflict", err)
+		}
+		if err := s.expenses.Delete(ctx, g, e.ID, 1); !errors.Is(err, db.ErrVersionConflict) {
+			t.Errorf("stale expense delete = %v, want ErrVersionConflict", err)
+		}
+		d, err := s.expenses.Get(ctx, g, e.ID)
+		if err != nil || d.Version != 2 || d.Note != "dinner" {
+			t.Errorf("expense after failed writes = %+v, %v; want version 2 kept", d, err)
+		}
+
+		missing := uuid.New().String()
+		notFound := []struct {
+			name string
+			err  error
+		}{
+			{"user", func() error { _, err := s.users.GetByID(ctx, missing); return err }()},
+			{"group", func() error { _, err := s.groups.Get(ctx, missing); return err }()},
+			{"expense", func() error { _, err := s.expenses.Get(ctx, g, missing); return err }()},
+			{"expense in another group", func() error { _, err := s.expenses.Get(ctx, missing, e.ID); return err }()},
+			{"user update", s.users.Update(ctx, &types.User{ID: missing, Name: "x", Version: 1})},
+			{"group update", s.groups.Update(ctx, &types.Group{ID: missing, Name: "x", Version: 1})},
+			{"expense delete", s.expenses.Delete(ctx, g, missing, 1)},
+		}
+		for _, tt := range notFound {
+			var nf *db.NotFoundError
+			if !errors.Is(tt.err, db.ErrNotFound) || !errors.As(tt.err, &nf) {
+				t.Errorf("%s = %v, want NotFound", tt.name, tt.err)
+			}
+		}
+	})
+}
+
+// wantBalances compares the group's non-zero balances; an account that has
+// gone back to zero may or may not still be listed.
+func wantBalances(t *testing.T, s stores, groupID string, want map[string]types.Money) {
+	t.Helper()
+	got, err := s.expenses.Balances(context.Background(), groupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum types.Money
+	for u, bal := range got {
+		sum += bal
+		if bal == 0 {
+			delete(got, u)
+		}
+	}
+	if sum != 0 {
+		t.Errorf("balances sum to %d, want 0", sum)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("balances = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,373 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Traced*Store wrap a store so every method is a span named after the
+// interface and method ("ExpenseStore.Balances"), with the Postgres and
+// Redis work it does underneath as children. Not embedding the interface is
+// deliberate: a method added to the store and not here won't compile.
+
+var tracer = otel.Tracer("github.com/akarshgo/paysplit/db")
+
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+}
+
+// endSpan ends span and hands err back. Domain errors are the caller's
+// mistake, not the store's, so only other errors mark the span failed.
+func endSpan(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		var notFound *NotFoundError
+		var conflict *ConflictError
+		var invalid *ValidationError
+		var forbidden *ForbiddenError
+		if !errors.As(err, &notFound) && !errors.As(err, &conflict) && !errors.As(err, &invalid) && !errors.As(err, &forbidden) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+	return err
+}
+
+type TracedUserStore struct {
+	inner UserStore
+}
+
+func NewTracedUserStore(inner UserStore) *TracedUserStore {
+	return &TracedUserStore{inner: inner}
+}
+
+func (s *TracedUserStore) Create(ctx context.Context, u *types.User) error {
+	ctx, span := startSpan(ctx, "UserStore.Create")
+	return endSpan(span, s.inner.Create(ctx, u))
+}
+
+func (s *TracedUserStore) GetByID(ctx context.Context, id string) (*types.User, error) {
+	ctx, span := startSpan(ctx, "UserStore.GetByID")
+	out, err := s.inner.GetByID(ctx, id)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedUserStore) Find(ctx context.Context, f UserFilter, limit, offset int) ([]*types.User, error) {
+	ctx, span := startSpan(ctx, "UserStore.Find")
+	out, err := s.inner.Find(ctx, f, limit, offset)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedUserStore) Update(ctx context.Context, u *types.User) error {
+	ctx, span := startSpan(ctx, "UserStore.Update")
+	return endSpan(span, s.inner.Update(ctx, u))
+}
+
+func (s *TracedUserStore) UpsertUPI(ctx context.Context, userID, vpa string) error {
+	ctx, span := startSpan(ctx, "UserStore.UpsertUPI")
+	return endSpan(span, s.inner.UpsertUPI(ctx, userID, vpa))
+}
+
+func (s *TracedUserStore) Delete(ctx context.Context, id string, version int64) error {
+	ctx, span := startSpan(ctx, "UserStore.Delete")
+	return endSpan(span, s.inner.Delete(ctx, id, version))
+}
+
+func (s *TracedUserStore) GetByEmail(ctx context.Context, email string) (*types.User, error) {
+	ctx, span := startSpan(ctx, "UserStore.GetByEmail")
+	out, err := s.inner.GetByEmail(ctx, email)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedUserStore) GetByPhone(ctx context.Context, phone string) (*types.User, error) {
+	ctx, span := startSpan(ctx, "UserStore.GetByPhone")
+	out, err := s.inner.GetByPhone(ctx, phone)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedUserStore) GetByUPI(ctx context.Context, vpa string) (*types.User, error) {
+	ctx, span := startSpan(ctx, "UserStore.GetByUPI")
+	out, err := s.inner.GetByUPI(ctx, vpa)
+	return out, endSpan(span, err)
+}
+
+type TracedGroupStore struct {
+	inner GroupStore
+}
+
+func NewTracedGroupStore(inner GroupStore) *TracedGroupStore {
+	return &TracedGroupStore{inner: inner}
+}
+
+func (s *TracedGroupStore) Create(ctx context.Context, g *types.Group) (string, error) {
+	ctx, span := startSpan(ctx, "GroupStore.Create")
+	out, err := s.inner.Create(ctx, g)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedGroupStore) Get(ctx context.Context, id string) (*types.Group, error) {
+	ctx, span := startSpan(ctx, "GroupStore.Get")
+	out, err := s.inner.Get(ctx, id)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedGroupStore) Update(ctx context.Context, g *types.Group) error {
+	ctx, span := startSpan(ctx, "GroupStore.Update")
+	return endSpan(span, s.inner.Update(ctx, g))
+}
+
+func (s *TracedGroupStore) ListByUser(ctx context.Context, userID string) ([]*types.Group, error) {
+	ctx, span := startSpan(ctx, "GroupStore.ListByUser")
+	out, err := s.inner.ListByUser(ctx, userID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedGroupStore) AddMember(ctx context.Context, groupID, userID string) error {
+	ctx, span := startSpan(ctx, "GroupStore.AddMember")
+	return endSpan(span, s.inner.AddMember(ctx, groupID, userID))
+}
+
+func (s *TracedGroupStore) ListMembers(ctx context.Context, groupID string) ([]string, error) {
+	ctx, span := startSpan(ctx, "GroupStore.ListMembers")
+	out, err := s.inner.ListMembers(ctx, groupID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedGroupStore) AddCategory(ctx context.Context, c *types.GroupCategory) error {
+	ctx, span := startSpan(ctx, "GroupStore.AddCategory")
+	return endSpan(span, s.inner.AddCategory(ctx, c))
+}
+
+func (s *TracedGroupStore) ListCategories(ctx context.Context, groupID string) ([]*types.GroupCategory, error) {
+	ctx, span := startSpan(ctx, "GroupStore.ListCategories")
+	out, err := s.inner.ListCategories(ctx, groupID)
+	return out, endSpan(span, err)
+}
+
+type TracedExpenseStore struct {
+	inner ExpenseStore
+}
+
+func NewTracedExpenseStore(inner ExpenseStore) *TracedExpenseStore {
+	return &TracedExpenseStore{inner: inner}
+}
+
+func (s *TracedExpenseStore) Create(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) (string, error) {
+	ctx, span := startSpan(ctx, "ExpenseStore.Create")
+	out, err := s.inner.Create(ctx, e, splits)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedExpenseStore) ListByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.Expense, string, error) {
+	ctx, span := startSpan(ctx, "ExpenseStore.ListByGroup")
+	out, next, err := s.inner.ListByGroup(ctx, groupID, f, limit, cursor)
+	return out, next, endSpan(span, err)
+}
+
+func (s *TracedExpenseStore) ListDetailsByGroup(ctx context.Context, groupID string, f ExpenseFilter, limit int, cursor string) ([]*types.ExpenseDetail, string, error) {
+	ctx, span := startSpan(ctx, "ExpenseStore.ListDetailsByGroup")
+	out, next, err := s.inner.ListDetailsByGroup(ctx, groupID, f, limit, cursor)
+	return out, next, endSpan(span, err)
+}
+
+func (s *TracedExpenseStore) Get(ctx context.Context, groupID, id string) (*types.ExpenseDetail, error) {
+	ctx, span := startSpan(ctx, "ExpenseStore.Get")
+	out, err := s.inner.Get(ctx, groupID, id)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedExpenseStore) Update(ctx context.Context, e *types.Expense, splits []types.ExpenseSplit) error {
+	ctx, span := startSpan(ctx, "ExpenseStore.Update")
+	return endSpan(span, s.inner.Update(ctx, e, splits))
+}
+
+func (s *TracedExpenseStore) Delete(ctx context.Context, groupID, id string, version int64) error {
+	ctx, span := startSpan(ctx, "ExpenseStore.Delete")
+	return endSpan(span, s.inner.Delete(ctx, groupID, id, version))
+}
+
+func (s *TracedExpenseStore) Balances(ctx context.Context, groupID string) (map[string]types.Money, error) {
+	ctx, span := startSpan(ctx, "ExpenseStore.Balances")
+	out, err := s.inner.Balances(ctx, groupID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedExpenseStore) TotalsByCategory(ctx context.Context, groupID string, f ExpenseFilter) ([]types.CategoryTotal, error) {
+	ctx, span := startSpan(ctx, "ExpenseStore.TotalsByCategory")
+	out, err := s.inner.TotalsByCategory(ctx, groupID, f)
+	return out, endSpan(span, err)
+}
+
+type TracedSettlementStore struct {
+	inner SettlementStore
+}
+
+func NewTracedSettlementStore(inner SettlementStore) *TracedSettlementStore {
+	return &TracedSettlementStore{inner: inner}
+}
+
+func (s *TracedSettlementStore) Create(ctx context.Context, st *types.Settlement) (string, error) {
+	ctx, span := startSpan(ctx, "SettlementStore.Create")
+	out, err := s.inner.Create(ctx, st)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedSettlementStore) CreateBatch(ctx context.Context, in []*types.Settlement) ([]string, error) {
+	ctx, span := startSpan(ctx, "SettlementStore.CreateBatch")
+	out, err := s.inner.CreateBatch(ctx, in)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedSettlementStore) ListByGroup(ctx context.Context, groupID string) ([]*types.Settlement, error) {
+	ctx, span := startSpan(ctx, "SettlementStore.ListByGroup")
+	out, err := s.inner.ListByGroup(ctx, groupID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedSettlementStore) Get(ctx context.Context, groupID, id string) (*types.Settlement, error) {
+	ctx, span := startSpan(ctx, "SettlementStore.Get")
+	out, err := s.inner.Get(ctx, groupID, id)
+	return out, endSpan(span, err)
+}
+
+type TracedLedgerStore struct {
+	inner LedgerStore
+}
+
+func NewTracedLedgerStore(inner LedgerStore) *TracedLedgerStore {
+	return &TracedLedgerStore{inner: inner}
+}
+
+func (s *TracedLedgerStore) Balances(ctx context.Context, groupID string, since int64) (map[string]types.Money, int64, error) {
+	ctx, span := startSpan(ctx, "LedgerStore.Balances")
+	out, version, err := s.inner.Balances(ctx, groupID, since)
+	return out, version, endSpan(span, err)
+}
+
+func (s *TracedLedgerStore) Recompute(ctx context.Context, groupID string) (map[string]types.Money, error) {
+	ctx, span := startSpan(ctx, "LedgerStore.Recompute")
+	out, err := s.inner.Recompute(ctx, groupID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedLedgerStore) Journals(ctx context.Context, groupID, sourceType, sourceID string) ([]*types.Journal, error) {
+	ctx, span := startSpan(ctx, "LedgerStore.Journals")
+	out, err := s.inner.Journals(ctx, groupID, sourceType, sourceID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedLedgerStore) Verify(ctx context.Context, groupID string) ([]types.LedgerIssue, error) {
+	ctx, span := startSpan(ctx, "LedgerStore.Verify")
+	out, err := s.inner.Verify(ctx, groupID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedLedgerStore) RebuildAccounts(ctx context.Context, groupID string) error {
+	ctx, span := startSpan(ctx, "LedgerStore.RebuildAccounts")
+	return endSpan(span, s.inner.RebuildAccounts(ctx, groupID))
+}
+
+func (s *TracedLedgerStore) Resync(ctx context.Context, groupID, sourceType, sourceID string) error {
+	ctx, span := startSpan(ctx, "LedgerStore.Resync")
+	return endSpan(span, s.inner.Resync(ctx, groupID, sourceType, sourceID))
+}
+
+type TracedActivityStore struct {
+	inner ActivityStore
+}
+
+func NewTracedActivityStore(inner ActivityStore) *TracedActivityStore {
+	return &TracedActivityStore{inner: inner}
+}
+
+func (s *TracedActivityStore) Record(ctx context.Context, a *types.Activity) error {
+	ctx, span := startSpan(ctx, "ActivityStore.Record")
+	return endSpan(span, s.inner.Record(ctx, a))
+}
+
+func (s *TracedActivityStore) ListByGroup(ctx context.Context, groupID string, since *time.Time, limit int, cursor string) ([]*types.Activity, string, error) {
+	ctx, span := startSpan(ctx, "ActivityStore.ListByGroup")
+	out, next, err := s.inner.ListByGroup(ctx, groupID, since, limit, cursor)
+	return out, next, endSpan(span, err)
+}
+
+type TracedCommentStore struct {
+	inner CommentStore
+}
+
+func NewTracedCommentStore(inner CommentStore) *TracedCommentStore {
+	return &TracedCommentStore{inner: inner}
+}
+
+func (s *TracedCommentStore) Create(ctx context.Context, c *types.Comment) error {
+	ctx, span := startSpan(ctx, "CommentStore.Create")
+	return endSpan(span, s.inner.Create(ctx, c))
+}
+
+func (s *TracedCommentStore) ListByTarget(ctx context.Context, groupID, targetType, targetID string) ([]*types.Comment, error) {
+	ctx, span := startSpan(ctx, "CommentStore.ListByTarget")
+	out, err := s.inner.ListByTarget(ctx, groupID, targetType, targetID)
+	return out, endSpan(span, err)
+}
+
+type TracedAttachmentStore struct {
+	inner AttachmentStore
+}
+
+func NewTracedAttachmentStore(inner AttachmentStore) *TracedAttachmentStore {
+	return &TracedAttachmentStore{inner: inner}
+}
+
+func (s *TracedAttachmentStore) Create(ctx context.Context, a *types.Attachment) error {
+	ctx, span := startSpan(ctx, "AttachmentStore.Create")
+	return endSpan(span, s.inner.Create(ctx, a))
+}
+
+func (s *TracedAttachmentStore) Get(ctx context.Context, expenseID, id string) (*types.Attachment, error) {
+	ctx, span := startSpan(ctx, "AttachmentStore.Get")
+	out, err := s.inner.Get(ctx, expenseID, id)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedAttachmentStore) ListByExpense(ctx context.Context, expenseID string) ([]*types.Attachment, error) {
+	ctx, span := startSpan(ctx, "AttachmentStore.ListByExpense")
+	out, err := s.inner.ListByExpense(ctx, expenseID)
+	return out, endSpan(span, err)
+}
+
+func (s *TracedAttachmentStore) Delete(ctx context.Context, expenseID, id string) error {
+	ctx, span := startSpan(ctx, "AttachmentStore.Delete")
+	return endSpan(span, s.inner.Delete(ctx, expenseID, id))
+}
+
+type TracedAuditStore struct {
+	inner AuditStore
+}
+
+func NewTracedAuditStore(inner AuditStore) *TracedAuditStore {
+	return &TracedAuditStore{inner: inner}
+}
+
+func (s *TracedAuditStore) List(ctx context.Context, f AuditFilter, limit int, cursor string) ([]*types.AuditEntry, string, error) {
+	ctx, span := startSpan(ctx, "AuditStore.List")
+	out, next, err := s.inner.List(ctx, f, limit, cursor)
+	return out, next, endSpan(span, err)
+}
+
+var (
+	_ UserStore       = (*TracedUserStore)(nil)
+	_ GroupStore      = (*TracedGroupStore)(nil)
+	_ ExpenseStore    = (*TracedExpenseStore)(nil)
+	_ SettlementStore = (*TracedSettlementStore)(nil)
+	_ LedgerStore     = (*TracedLedgerStore)(nil)
+	_ ActivityStore   = (*TracedActivityStore)(nil)
+	_ CommentStore    = (*TracedCommentStore)(nil)
+	_ AttachmentStore = (*TracedAttachmentStore)(nil)
+	_ AuditStore      = (*TracedAuditStore)(nil)
+)
@@ -3,7 +3,6 @@ package db
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -23,12 +22,17 @@ type UserStore interface {
 	Create(ctx context.Context, u *types.User) error
 	GetByID(ctx context.Context, id string) (*types.User, error)
 	Find(ctx context.Context, f UserFilter, limit, offset int) ([]*types.User, error)
+	// Update only applies if u.Version is still current (ErrVersionConflict
+	// otherwise) and bumps u.Version; ErrNotFound if missing
 	Update(ctx context.Context, u *types.User) error
 	UpsertUPI(ctx context.Context, userID, vpa string) error
-	Delete(ctx context.Context, id string) error
+	// Delete only applies if version is still current; ErrNotFound if missing
+	Delete(ctx context.Context, id string, version int64) error
 	// auth helpers
 	GetByEmail(ctx context.Context, email string) (*types.User, error)
 	GetByPhone(ctx context.Context, phone string) (*types.User, error)
+	// GetByUPI matches the VPA case-insensitively (PSP handles are not case sensitive)
+	GetByUPI(ctx context.Context, vpa string) (*types.User, error)
 }
 
 type PostgresUserStore struct {
@@ -43,45 +47,66 @@ func NewPostgresUserStore(db *sql.DB) *PostgresUserStore {
 
 func (s *PostgresUserStore) Create(ctx context.Context, u *types.User) error {
 	if strings.TrimSpace(u.Name) == "" {
-		return errors.New("name required")
+		return Invalid("name", "required")
 	}
 	id := uuid.New().String()
 	now := time.Now()
 
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO users (id, name, email, phone, upi_vpa, created_at)
-		VALUES ($1,$2,$3,$4,$5,$6)
-	`, id, u.Name, nullStr(u.Email), nullStr(u.Phone), nullStr(u.UPI), now)
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO users (id, name, email, phone, upi_vpa, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6)
+		`, id, u.Name, nullStr(u.Email), nullStr(u.Phone), nullStr(u.UPI), now)
+		if err != nil {
+			return err
+		}
+		after := *u
+		after.ID, after.Version, after.CreatedAt = id, 1, now
+		return audit(ctx, tx, "", "user", id, "create", nil, after)
+	})
 	if err != nil {
-		return err
+		return translate(err, "user", "")
 	}
-	u.ID = id
+	u.ID, u.Version = id, 1
 	u.CreatedAt = now
 	return nil
 }
 
 func (s *PostgresUserStore) GetByID(ctx context.Context, id string) (*types.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, name, email, phone, upi_vpa, created_at
+		SELECT id, name, email, phone, upi_vpa, version, created_at
 		FROM users WHERE id = $1
 	`, id)
-	return scanUser(row)
+	u, err := scanUser(row)
+	return u, translate(err, "user", id)
 }
 
 func (s *PostgresUserStore) GetByEmail(ctx context.Context, email string) (*types.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, name, email, phone, upi_vpa, created_at
+		SELECT id, name, email, phone, upi_vpa, version, created_at
 		FROM users WHERE email = $1
 	`, email)
-	return scanUser(row)
+	u, err := scanUser(row)
+	return u, translate(err, "user", "")
 }
 
 func (s *PostgresUserStore) GetByPhone(ctx context.Context, phone string) (*types.User, error) {
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, name, email, phone, upi_vpa, created_at
+		SELECT id, name, email, phone, upi_vpa, version, created_at
 		FROM users WHERE phone = $1
 	`, phone)
-	return scanUser(row)
+	u, err := scanUser(row)
+	return u, translate(err, "user", "")
+}
+
+func (s *PostgresUserStore) GetByUPI(ctx context.Context, vpa string) (*types.User, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, name, email, phone, upi_vpa, version, created_at
+		FROM users WHERE lower(upi_vpa) = lower($1)
+		LIMIT 1
+	`, strings.TrimSpace(vpa))
+	u, err := scanUser(row)
+	return u, translate(err, "user", "")
 }
 
 func (s *PostgresUserStore) Find(ctx context.Context, f UserFilter, limit, offset int) ([]*types.User, error) {
@@ -106,7 +131,7 @@ func (s *PostgresUserStore) Find(ctx context.Context, f UserFilter, limit, offse
 		i++
 	}
 	q := `
-		SELECT id, name, email, phone, upi_vpa, created_at
+		SELECT id, name, email, phone, upi_vpa, version, created_at
 		FROM users`
 	if len(where) > 0 {
 		q += " WHERE " + strings.Join(where, " AND ")
@@ -140,36 +165,82 @@ func (s *PostgresUserStore) Find(ctx context.Context, f UserFilter, limit, offse
 
 func (s *PostgresUserStore) Update(ctx context.Context, u *types.User) error {
 	if strings.TrimSpace(u.ID) == "" {
-		return errors.New("id required")
+		return Invalid("id", "required")
 	}
 	if strings.TrimSpace(u.Name) == "" {
-		return errors.New("name required")
+		return Invalid("name", "required")
 	}
-	_, err := s.db.ExecContext(ctx, `
-		UPDATE users
-		SET name = $2, email = $3, phone = $4, upi_vpa = $5
-		WHERE id = $1
-	`, u.ID, u.Name, nullStr(u.Email), nullStr(u.Phone), nullStr(u.UPI))
-	return err
-}
-
-func (s *PostgresUserStore) UpsertUPI(ctx context.Context, userID, vpa string) error {
-	res, err := s.db.ExecContext(ctx, `
-		UPDATE users SET upi_vpa = $2 WHERE id = $1
-	`, userID, vpa)
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := lockUser(ctx, tx, u.ID)
+		if err != nil {
+			return err
+		}
+		if before.Version != u.Version {
+			return ErrVersionConflict
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users
+			SET name = $2, email = $3, phone = $4, upi_vpa = $5, version = version + 1
+			WHERE id = $1
+		`, u.ID, u.Name, nullStr(u.Email), nullStr(u.Phone), nullStr(u.UPI))
+		if err != nil {
+			return err
+		}
+		after := *u
+		after.Version, after.CreatedAt = before.Version+1, before.CreatedAt
+		return audit(ctx, tx, "", "user", u.ID, "update", before, after)
+	})
 	if err != nil {
-		return err
-	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return sql.ErrNoRows
+		return translate(err, "user", u.ID)
 	}
+	u.Version++
 	return nil
 }
 
-func (s *PostgresUserStore) Delete(ctx context.Context, id string) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
-	return err
+func (s *PostgresUserStore) UpsertUPI(ctx context.Context, userID, vpa string) error {
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := lockUser(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE users SET upi_vpa = $2, version = version + 1 WHERE id = $1
+		`, userID, vpa); err != nil {
+			return err
+		}
+		after := *before
+		after.UPI, after.Version = &vpa, before.Version+1
+		return audit(ctx, tx, "", "user", userID, "update", before, after)
+	})
+	return translate(err, "user", userID)
+}
+
+func (s *PostgresUserStore) Delete(ctx context.Context, id string, version int64) error {
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		before, err := lockUser(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if before.Version != version {
+			return ErrVersionConflict
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+			return err
+		}
+		return audit(ctx, tx, "", "user", id, "delete", before, nil)
+	})
+	return translate(err, "user", id)
+}
+
+// lockUser reads the user for update inside tx; sql.ErrNoRows if missing,
+// callers translate
+func lockUser(ctx context.Context, tx *sql.Tx, id string) (*types.User, error) {
+	row := tx.QueryRowContext(ctx, `
+		SELECT id, name, email, phone, upi_vpa, version, created_at
+		FROM users WHERE id = $1
+		FOR UPDATE
+	`, id)
+	return scanUser(row)
 }
 
 // --- helpers ---
@@ -182,7 +253,7 @@ func scanUser(scanner interface{ Scan(dest ...any) error }) (*types.User, error)
 		upiNS     sql.NullString
 		createdAt time.Time
 	)
-	if err := scanner.Scan(&u.ID, &u.Name, &emailNS, &phoneNS, &upiNS, &createdAt); err != nil {
+	if err := scanner.Scan(&u.ID, &u.Name, &emailNS, &phoneNS, &upiNS, &u.Version, &createdAt); err != nil {
 		return nil, err
 	}
 	if emailNS.Valid {
@@ -204,3 +275,10 @@ func nullStr(s *string) any {
 	}
 	return sql.NullString{String: *s, Valid: true}
 }
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: s, Valid: true}
+}
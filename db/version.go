@@ -0,0 +1,5 @@
+package db
+
+// ErrVersionConflict means the row changed since the caller read it: the
+// version they sent is not the current one.
+var ErrVersionConflict error = &ConflictError{Code: "version_conflict", Message: "resource was changed by someone else, reload and retry"}
@@ -0,0 +1,240 @@
+// Package fsck checks the invariants the API relies on but the schema
+// can't enforce on its own: splits summing to the expense, everyone in a
+// split being a group member, and the ledger matching the expenses. Only
+// repairs that can't lose information are offered.
+package fsck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/akarshgo/paysplit/db"
+)
+
+type Problem struct {
+	Check      string `json:"check"`
+	GroupID    string `json:"group_id,omitempty"`
+	Entity     string `json:"entity"` // what EntityID is: "expense", "split", "settlement", "journal", "account"
+	EntityID   string `json:"entity_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	Detail     string `json:"detail"`
+	Repairable bool   `json:"repairable"`
+	Repaired   bool   `json:"repaired,omitempty"`
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-22s", p.Check)
+	for _, kv := range [][2]string{{"group", p.GroupID}, {p.Entity, p.EntityID}, {"user", p.UserID}} {
+		if kv[1] != "" {
+			fmt.Fprintf(&b, " %s=%s", kv[0], kv[1])
+		}
+	}
+	fmt.Fprintf(&b, " %s", p.Detail)
+	if p.Repaired {
+		b.WriteString(" [repaired]")
+	} else if p.Repairable {
+		b.WriteString(" [repairable]")
+	}
+	return b.String()
+}
+
+type Checker struct {
+	db     *sql.DB
+	groups db.GroupStore // membership repairs go through the store so they are audited
+	ledger db.LedgerStore
+}
+
+func New(sqlDB *sql.DB, groups db.GroupStore, ledger db.LedgerStore) *Checker {
+	return &Checker{db: sqlDB, groups: groups, ledger: ledger}
+}
+
+// each query returns group_id, entity_id, user_id, detail; $1 is the group
+// to check or NULL for all of them
+type check struct {
+	name       string
+	entity     string
+	repairable bool
+	query      string
+}
+
+var checks = []check{
+	{"split_sum_mismatch", "expense", false, `
+		SELECT e.group_id::text, e.id::text, '',
+			format('amount_paise=%s splits=%s', e.amount_paise, COALESCE(SUM(sp.exact), 0))
+		FROM expenses e
+		LEFT JOIN expense_splits sp ON sp.expense_id = e.id
+		WHERE $1::uuid IS NULL OR e.group_id = $1
+		GROUP BY e.id
+		HAVING COALESCE(SUM(sp.exact), 0) <> e.amount_paise`},
+	{"negative_split", "split", false, `
+		SELECT e.group_id::text, sp.id::text, sp.user_id::text, format('expense=%s exact=%s', e.id, sp.exact)
+		FROM expense_splits sp
+		JOIN expenses e ON e.id = sp.expense_id
+		WHERE sp.exact < 0 AND ($1::uuid IS NULL OR e.group_id = $1)`},
+	{"duplicate_split_user", "expense", false, `
+		SELECT e.group_id::text, e.id::text, sp.user_id::text, format('%s split rows', COUNT(*))
+		FROM expense_splits sp
+		JOIN expenses e ON e.id = sp.expense_id
+		WHERE $1::uuid IS NULL OR e.group_id = $1
+		GROUP BY e.group_id, e.id, sp.user_id
+		HAVING COUNT(*) > 1`},
+	// an orphan belongs to no group, so only a full run looks for them
+	{"orphan_split", "split", true, `
+		SELECT '', sp.id::text, sp.user_id::text, format('expense %s does not exist', sp.expense_id)
+		FROM expense_splits sp
+		WHERE $1::uuid IS NULL
+			AND NOT EXISTS (SELECT 1 FROM expenses e WHERE e.id = sp.expense_id)`},
+	// repairable when the user exists: they evidently took part, add them to the group
+	{"non_member", "expense", true, `
+		SELECT x.group_id::text, x.id::text, x.user_id::text, x.role
+		FROM (
+			SELECT e.group_id, e.id, e.paid_by AS user_id, 'payer is not a member' AS role
+			FROM expenses e
+			WHERE $1::uuid IS NULL OR e.group_id = $1
+			UNION
+			SELECT e.group_id, e.id, sp.user_id, 'split user is not a member'
+			FROM expenses e JOIN expense_splits sp ON sp.expense_id = e.id
+			WHERE $1::uuid IS NULL OR e.group_id = $1
+		) x
+		WHERE NOT EXISTS (SELECT 1 FROM group_members m WHERE m.group_id = x.group_id AND m.user_id = x.user_id)`},
+	{"settlement_non_member", "settlement", true, `
+		SELECT x.group_id::text, x.id::text, x.user_id::text, x.role
+		FROM (
+			SELECT group_id, id, from_user AS user_id, 'payer is not a member' AS role FROM settlements
+			WHERE $1::uuid IS NULL OR group_id = $1
+			UNION
+			SELECT group_id, id, to_user, 'payee is not a member' FROM settlements
+			WHERE $1::uuid IS NULL OR group_id = $1
+		) x
+		WHERE NOT EXISTS (SELECT 1 FROM group_members m WHERE m.group_id = x.group_id AND m.user_id = x.user_id)`},
+	{"bad_settlement", "settlement", false, `
+		SELECT group_id::text, id::text, from_user::text, format('from=%s to=%s amount=%s', from_user, to_user, amount)
+		FROM settlements
+		WHERE (from_user = to_user OR amount <= 0) AND ($1::uuid IS NULL OR group_id = $1)`},
+}
+
+// Run checks everything, or a single group when groupID is set.
+func (c *Checker) Run(ctx context.Context, groupID string) ([]Problem, error) {
+	var group any
+	if groupID != "" {
+		group = groupID
+	}
+	var out []Problem
+	for _, ch := range checks {
+		rows, err := c.db.QueryContext(ctx, ch.query, group)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ch.name, err)
+		}
+		for rows.Next() {
+			p := Problem{Check: ch.name, Entity: ch.entity, Repairable: ch.repairable}
+			if err := rows.Scan(&p.GroupID, &p.EntityID, &p.UserID, &p.Detail); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			out = append(out, p)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	issues, err := c.ledger.Verify(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("ledger: %w", err)
+	}
+	for _, is := range issues {
+		p := Problem{Check: "ledger_" + is.Kind, GroupID: is.GroupID, UserID: is.UserID, Detail: is.Detail}
+		switch is.Kind {
+		case "unbalanced_journal":
+			p.Entity, p.EntityID = "journal", is.JournalID // append-only, needs a human
+		case "account_mismatch", "unbalanced_group":
+			p.Entity, p.Repairable = "account", true
+		case "source_mismatch":
+			p.Entity, p.EntityID, p.Repairable = is.SourceType, is.SourceID, true
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Repair fixes the repairable problems found by Run(ctx, groupID), then
+// checks again: a problem only counts as repaired once it is gone.
+// Problems the repairs brought to light are appended to the result.
+// Account rebuilds run after resyncs so they see the final postings.
+func (c *Checker) Repair(ctx context.Context, groupID string, problems []Problem) ([]Problem, error) {
+	rebuild := map[string]bool{}
+	for i := range problems {
+		p := &problems[i]
+		if !p.Repairable {
+			continue
+		}
+		var err error
+		switch p.Check {
+		case "orphan_split":
+			_, err = c.db.ExecContext(ctx, `DELETE FROM expense_splits WHERE id = $1`, p.EntityID)
+		case "non_member", "settlement_non_member":
+			err = c.addMember(ctx, p)
+		case "ledger_source_mismatch":
+			err = c.ledger.Resync(ctx, p.GroupID, p.Entity, p.EntityID)
+		case "ledger_account_mismatch", "ledger_unbalanced_group":
+			rebuild[p.GroupID] = true
+		}
+		if err != nil {
+			return problems, fmt.Errorf("repair %s %s: %w", p.Check, p.EntityID, err)
+		}
+	}
+	for gid := range rebuild {
+		if err := c.ledger.RebuildAccounts(ctx, gid); err != nil {
+			return problems, fmt.Errorf("rebuild accounts %s: %w", gid, err)
+		}
+	}
+
+	left, err := c.Run(ctx, groupID)
+	if err != nil {
+		return problems, fmt.Errorf("recheck: %w", err)
+	}
+	remaining := make(map[problemKey]bool, len(left))
+	for _, p := range left {
+		remaining[p.key()] = true
+	}
+	seen := make(map[problemKey]bool, len(problems))
+	for i := range problems {
+		k := problems[i].key()
+		seen[k] = true
+		problems[i].Repaired = !remaining[k]
+	}
+	for _, p := range left {
+		if !seen[p.key()] {
+			problems = append(problems, p)
+		}
+	}
+	return problems, nil
+}
+
+// problemKey identifies a problem across runs; the detail may change.
+type problemKey struct {
+	check, groupID, entity, entityID, userID string
+}
+
+func (p Problem) key() problemKey {
+	return problemKey{p.Check, p.GroupID, p.Entity, p.EntityID, p.UserID}
+}
+
+// addMember only repairs when the user actually exists
+func (c *Checker) addMember(ctx context.Context, p *Problem) error {
+	var exists bool
+	if err := c.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, p.UserID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		p.Repairable = false
+		p.Detail += " (user does not exist)"
+		return nil
+	}
+	return c.groups.AddMember(ctx, p.GroupID, p.UserID)
+}
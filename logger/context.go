@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// WithContext returns ctx carrying l, normally the request's logger with
+// its request and trace IDs already attached.
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+var nop = zap.NewNop()
+
+// From returns the logger in ctx. Commands put theirs in the root context,
+// so it is only missing where nobody asked for logs, e.g. in tests; a no-op
+// logger is returned then.
+func From(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return nop
+}
@@ -1,18 +1,25 @@
 package logger
 
-import "go.uber.org/zap"
+import (
+	"github.com/akarshgo/paysplit/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
 
-var Log *zap.Logger
-
-func Init() {
-	l, err := zap.NewProduction() //json structured logger
+// New builds the logger for cfg, with phones, emails and VPAs masked.
+// Commands make one and pass it down; there is no package-level logger.
+func New(cfg config.Log) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	zc := zap.NewProductionConfig() //json structured logger
+	if cfg.Format == "console" {
+		zc = zap.NewDevelopmentConfig()
+	}
+	zc.Level = zap.NewAtomicLevelAt(level)
 
-	Log = l
-}
-
-func Sync() {
-	_ = Log.Sync()
+	return zc.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return redactCore{core}
+	}))
 }
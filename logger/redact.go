@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Phone numbers, emails and UPI VPAs never reach the logs in full. The
+// core below masks them in every string, error, stringer and structured
+// (zap.Any, Reflect, Object, Array) field, whatever the field is called,
+// so a stray zap.Error(err) can't leak one either.
+
+var (
+	// emails and VPAs both look like handle@domain
+	handleAt = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*(@|%40)([A-Za-z0-9.-]+)`)
+	// an Indian mobile number standing alone: ten digits starting 6-9,
+	// maybe after +91 or 0. A 12-digit UPI ref or a number inside a UUID
+	// (preceded by '-') doesn't match; neither does a bare 91 prefix, it
+	// can't be told from a ref.
+	phone = regexp.MustCompile(`(^|[^0-9A-Za-z%-])((?:(?:\+|%2[Bb])91[ -]?|0)?[6-9]\d{5})(\d{4})($|[^0-9A-Za-z-])`)
+)
+
+// Redact masks emails, VPAs and phone numbers in s: a***@example.com,
+// a***@okhdfc, ******3210.
+func Redact(s string) string {
+	s = handleAt.ReplaceAllString(s, "$1***$2$3")
+	mask := func(m string) string {
+		p := phone.FindStringSubmatch(m)
+		return p[1] + strings.Repeat("*", len(p[2])) + p[3] + p[4]
+	}
+	// twice: a match uses up the separator the next number needs ("a,b")
+	return phone.ReplaceAllStringFunc(phone.ReplaceAllStringFunc(s, mask), mask)
+}
+
+// sensitiveParams are masked whole, whatever their value looks like.
+var sensitiveParams = map[string]bool{"email": true, "phone": true, "vpa": true, "upi": true, "pa": true}
+
+// RedactQuery masks a raw query string for logging.
+func RedactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return Redact(raw)
+	}
+	for k, vs := range q {
+		for i, v := range vs {
+			if sensitiveParams[strings.ToLower(k)] && v != "" {
+				vs[i] = "***"
+			} else {
+				vs[i] = Redact(v)
+			}
+		}
+	}
+	return strings.ReplaceAll(q.Encode(), "%2A", "*")
+}
+
+// redactCore masks string and error fields before they are encoded.
+type redactCore struct {
+	zapcore.Core
+}
+
+func (c redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactCore{c.Core.With(redactFields(fields))}
+}
+
+func (c redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = Redact(f.String)
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok {
+				f = zap.String(f.Key, Redact(err.Error()))
+			}
+		case zapcore.StringerType:
+			if s, ok := f.Interface.(fmt.Stringer); ok {
+				f = zap.String(f.Key, Redact(s.String()))
+			}
+		case zapcore.ByteStringType:
+			if b, ok := f.Interface.([]byte); ok {
+				f = zap.ByteString(f.Key, []byte(Redact(string(b))))
+			}
+		case zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+			f = redactStructured(f)
+		}
+		out[i] = f
+	}
+	return out
+}
+
+// redactStructured renders the field to JSON, as the encoder would, and
+// masks every string in it, map keys included. A field that can't be
+// marshalled is logged as its error, masked too.
+func redactStructured(f zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc) // a marshalling error lands in enc.Fields[f.Key+"Error"]
+	if msg, ok := enc.Fields[f.Key+"Error"].(string); ok {
+		return zap.String(f.Key+"Error", Redact(msg))
+	}
+	b, err := json.Marshal(enc.Fields[f.Key])
+	var v any
+	if err == nil {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.UseNumber() // int64 paise stay exact
+		err = d.Decode(&v)
+	}
+	if err != nil {
+		return zap.String(f.Key+"Error", Redact(err.Error()))
+	}
+	return zap.Reflect(f.Key, redactValue(v))
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return Redact(v)
+	case []any:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[Redact(k)] = redactValue(e)
+		}
+		return out
+	}
+	return v
+}
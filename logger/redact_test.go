@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"asha@example.com", "a***@example.com"},
+		{"paid asha@okhdfc", "paid a***@okhdfc"},
+		{"pa=asha%40okhdfc", "pa=a***%40okhdfc"},
+		{"call 9876543210", "call ******3210"},
+		{"+919876543210", "*********3210"},
+		{"09876543210", "*******3210"},
+		{"9876543210,8765432109", "******3210,******2109"},
+		// UPI refs, bank numbers and IDs aren't phones
+		{"UPI Ref 412345678901", "UPI Ref 412345678901"},
+		{"ref 712345678901", "ref 712345678901"},
+		{"A/c 1234567890", "A/c 1234567890"},
+		{"6b1c9d3e-8f2a-4c1b-9e7d-987654321098", "6b1c9d3e-8f2a-4c1b-9e7d-987654321098"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.in); got != tt.want {
+			t.Errorf("Redact(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+type member struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone"`
+}
+
+func (m member) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", m.Name)
+	enc.AddString("phone", m.Phone)
+	return nil
+}
+
+func TestRedactCoreStructuredFields(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	log := zap.New(redactCore{core})
+
+	log.Info("balances",
+		zap.Any("accounts", map[string]int64{"asha@okhdfc": 12000}),
+		zap.Reflect("member", member{Name: "Asha", Phone: "9876543210"}),
+		zap.Object("payer", member{Name: "Bala", Phone: "+918765432109"}),
+		zap.Strings("vpas", []string{"bala@okaxis", "ref 412345678901"}),
+		zap.Any("ref", 412345678901),
+	)
+
+	got, err := json.Marshal(logs.All()[0].ContextMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"accounts":{"a***@okhdfc":12000},"member":{"name":"Asha","phone":"******3210"},` +
+		`"payer":{"name":"Bala","phone":"*********2109"},"ref":412345678901,"vpas":["b***@okaxis","ref 412345678901"]}`
+	if string(got) != want {
+		t.Errorf("fields = %s\nwant     %s", got, want)
+	}
+}
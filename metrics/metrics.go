@@ -0,0 +1,101 @@
+// Package metrics holds the Prometheus collectors, registered on the
+// default registry and served at /metrics. Names are paysplit_*; label
+// values are kept to route templates, commands and enums so cardinality
+// stays bounded.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "paysplit"
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	HTTPInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being served.",
+	})
+
+	RedisDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redis_command_duration_seconds",
+		Help:      "Redis command latency by command and outcome (ok, miss, error).",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, 1},
+	}, []string{"command", "result"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests refused with 429, by rate limit policy route.",
+	}, []string{"policy"})
+
+	ExpensesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "expenses_created_total",
+		Help:      "Expenses created.",
+	})
+
+	ExpensePaise = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "expense_volume_paise_total",
+		Help:      "Sum of created expense amounts, in paise.",
+	})
+
+	SettlementsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "settlements_created_total",
+		Help:      "Settlements recorded, by method.",
+	}, []string{"method"})
+
+	SettlementPaise = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "settlement_volume_paise_total",
+		Help:      "Sum of recorded settlement amounts, in paise.",
+	})
+
+	RemindersSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reminders_sent_total",
+		Help:      "Payment reminders sent, by channel (push, sms, email).",
+	}, []string{"channel"})
+)
+
+// ObserveHTTP records one finished request.
+func ObserveHTTP(method, route string, status int, seconds float64) {
+	s := strconv.Itoa(status)
+	HTTPRequests.WithLabelValues(method, route, s).Inc()
+	HTTPDuration.WithLabelValues(method, route, s).Observe(seconds)
+}
+
+// SettlementMethod bounds the method label; clients may send anything.
+func SettlementMethod(method string) string {
+	switch method {
+	case "upi", "cash", "bank":
+		return method
+	}
+	return "other"
+}
+
+// RegisterDB exports the pool stats (open, in use, idle, waits) of sqlDB.
+func RegisterDB(sqlDB *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(sqlDB, name))
+}
@@ -0,0 +1,222 @@
+// Package migrate applies the numbered SQL files in migrations/, which are
+// embedded in the binary. Files are NNNN_name.up.sql / NNNN_name.down.sql;
+// each runs in its own transaction together with its schema_migrations row,
+// so a failed migration leaves nothing half applied.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// arbitrary, only has to be the same for every instance
+const lockKey = 7246100
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+func (m Migration) String() string { return fmt.Sprintf("%04d_%s", m.Version, m.Name) }
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"` // nil if pending
+	Unknown   bool       `json:"unknown,omitempty"`    // applied but not in this binary
+}
+
+type applied struct {
+	name string
+	at   time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration // by version
+}
+
+func New(sqlDB *sql.DB) (*Migrator, error) {
+	ms, err := load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: sqlDB, migrations: ms}, nil
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+func load(fsys fs.FS) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, p := range paths {
+		base := p[len("migrations/"):]
+		m := fileName.FindStringSubmatch(base)
+		if m == nil {
+			return nil, fmt.Errorf("migrate: bad file name %s", base)
+		}
+		v, _ := strconv.ParseInt(m[1], 10, 64)
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[v]
+		if mig == nil {
+			mig = &Migration{Version: v, Name: m[2]}
+			byVersion[v] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d used by %s and %s", v, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.up = string(b)
+		} else {
+			mig.down = string(b)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migrate: %s needs both an up and a down file", m)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Up applies every pending migration in order and returns what it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := run(ctx, conn, mig.up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migrate: %s up: %w", mig, err)
+			}
+			ran = append(ran, mig)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down rolls back the last steps applied migrations, newest first.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(ctx, func(conn *sql.Conn, done map[int64]applied) error {
+		for i := len(m.migrations) - 1; i >= 0 && len(ran) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if err := run(ctx, conn, mig.down, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+				return fmt.Errorf("migrate: %s down: %w", mig, err)
+			}
+			ran = append(ran, mig)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Status lists every known migration plus any applied ones this binary
+// doesn't know about (the database is newer than the code).
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var out []Status
+	err := m.locked(ctx, func(_ *sql.Conn, done map[int64]applied) error {
+		for _, mig := range m.migrations {
+			st := Status{Version: mig.Version, Name: mig.Name}
+			if a, ok := done[mig.Version]; ok {
+				st.AppliedAt = &a.at
+				delete(done, mig.Version)
+			}
+			out = append(out, st)
+		}
+		for v, a := range done {
+			out = append(out, Status{Version: v, Name: a.name, AppliedAt: &a.at, Unknown: true})
+		}
+		return nil
+	})
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, err
+}
+
+// locked runs fn on one connection holding a session advisory lock, so
+// instances starting together don't race to apply the same migration.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, done map[int64]applied) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("migrate: lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version    BIGINT PRIMARY KEY,
+		  name       TEXT NOT NULL,
+		  applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	done := map[int64]applied{}
+	for rows.Next() {
+		var v int64
+		var a applied
+		if err := rows.Scan(&v, &a.name, &a.at); err != nil {
+			rows.Close()
+			return err
+		}
+		done[v] = a
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+// run executes a migration script and its bookkeeping statement in one tx.
+func run(ctx context.Context, conn *sql.Conn, script, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// no args, so lib/pq sends it as a simple query and multiple statements work
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
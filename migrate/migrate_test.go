@@ -0,0 +1,216 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// testDB is a new, empty database on the server DATABASE_URL points at,
+// dropped again when the test ends. Without DATABASE_URL the test skips.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		t.Skip("DATABASE_URL must be a postgres:// URL to create a scratch database")
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("paysplit_migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE DATABASE ` + name); err != nil {
+		admin.Close()
+		t.Fatalf("create database: %v", err)
+	}
+	u.Path = "/" + name
+	sqlDB, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		if _, err := admin.Exec(`DROP DATABASE IF EXISTS ` + name); err != nil {
+			t.Errorf("drop database %s: %v", name, err)
+		}
+		admin.Close()
+	})
+	return sqlDB
+}
+
+func TestUpDownUp(t *testing.T) {
+	sqlDB := testDB(t)
+	ctx := context.Background()
+	m, err := New(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := len(m.migrations)
+
+	ran, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(ran) != all {
+		t.Fatalf("up ran %d migrations, want %d", len(ran), all)
+	}
+	assertStatus(t, m, true)
+
+	if ran, err = m.Up(ctx); err != nil || len(ran) != 0 {
+		t.Fatalf("second up = %v, %v; want nothing to do", ran, err)
+	}
+
+	if ran, err = m.Down(ctx, all); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(ran) != all || ran[0].Version != m.migrations[all-1].Version {
+		t.Fatalf("down ran %v, want all %d newest first", ran, all)
+	}
+	assertStatus(t, m, false)
+	var left []string
+	rows, err := sqlDB.QueryContext(ctx, `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_name <> 'schema_migrations'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		left = append(left, name)
+	}
+	rows.Close()
+	if len(left) > 0 {
+		t.Errorf("tables left after down: %s", strings.Join(left, ", "))
+	}
+
+	if ran, err = m.Up(ctx); err != nil || len(ran) != all {
+		t.Fatalf("up after down = %d migrations, %v; want %d", len(ran), err, all)
+	}
+	assertStatus(t, m, true)
+}
+
+func assertStatus(t *testing.T, m *Migrator, applied bool) {
+	t.Helper()
+	list, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(list) != len(m.migrations) {
+		t.Fatalf("status lists %d migrations, want %d", len(list), len(m.migrations))
+	}
+	for _, st := range list {
+		if st.Unknown || (st.AppliedAt != nil) != applied {
+			t.Errorf("%04d_%s: applied_at %v, unknown %v; want applied %v", st.Version, st.Name, st.AppliedAt, st.Unknown, applied)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(sql string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(sql)} }
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []string // versions as Migration.String
+		wantErr string
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"migrations/0010_later.up.sql":   file("SELECT 10"),
+				"migrations/0010_later.down.sql": file("SELECT -10"),
+				"migrations/0002_first.up.sql":   file("SELECT 2"),
+				"migrations/0002_first.down.sql": file("SELECT -2"),
+			},
+			want: []string{"0002_first", "0010_later"},
+		},
+		{
+			name: "bad name",
+			fsys: fstest.MapFS{
+				"migrations/0001_init.sql": file("SELECT 1"),
+			},
+			wantErr: "bad file name 0001_init.sql",
+		},
+		{
+			name: "no version",
+			fsys: fstest.MapFS{
+				"migrations/init.up.sql": file("SELECT 1"),
+			},
+			wantErr: "bad file name init.up.sql",
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"migrations/0001_users.up.sql":    file("SELECT 1"),
+				"migrations/0001_users.down.sql":  file("SELECT -1"),
+				"migrations/0001_groups.up.sql":   file("SELECT 1"),
+				"migrations/0001_groups.down.sql": file("SELECT -1"),
+			},
+			wantErr: "version 1 used by",
+		},
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{
+				"migrations/0001_init.up.sql":   file("SELECT 1"),
+				"migrations/0001_init.down.sql": file("SELECT -1"),
+				"migrations/0002_more.up.sql":   file("SELECT 2"),
+			},
+			wantErr: "0002_more needs both an up and a down file",
+		},
+		{
+			name: "missing up",
+			fsys: fstest.MapFS{
+				"migrations/0001_init.down.sql": file("SELECT -1"),
+			},
+			wantErr: "0001_init needs both an up and a down file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := load(tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, len(got))
+			for i, m := range got {
+				names[i] = m.String()
+			}
+			if strings.Join(names, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("loaded %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+// The embedded files have to load, or the binary can't start.
+func TestLoadEmbedded(t *testing.T) {
+	ms, err := load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range ms {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d is %s, want versions numbered 1, 2, ... without gaps", i, m)
+		}
+	}
+}
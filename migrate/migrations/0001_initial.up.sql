@@ -0,0 +1,62 @@
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS users (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name        TEXT NOT NULL,
+  email       TEXT UNIQUE,
+  phone       TEXT UNIQUE,
+  upi_vpa     TEXT,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name        TEXT NOT NULL,
+  created_by  UUID NOT NULL REFERENCES users(id),
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+  group_id    UUID REFERENCES groups(id) ON DELETE CASCADE,
+  user_id     UUID REFERENCES users(id) ON DELETE CASCADE,
+  role        TEXT NOT NULL DEFAULT 'member',  -- admin|member
+  added_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (group_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS expenses (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id      UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+  paid_by       UUID NOT NULL REFERENCES users(id),
+  amount_paise  BIGINT NOT NULL,
+  currency      TEXT   NOT NULL DEFAULT 'INR',
+  note          TEXT,
+  split_kind    TEXT   NOT NULL,   -- equal|shares|percent|exact
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS expense_splits (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+  user_id    UUID NOT NULL REFERENCES users(id),
+  exact      BIGINT NOT NULL
+);
+
+-- helpful indexes
+CREATE INDEX IF NOT EXISTS idx_expenses_group ON expenses(group_id);
+CREATE INDEX IF NOT EXISTS idx_splits_expense ON expense_splits(expense_id);
+CREATE INDEX IF NOT EXISTS idx_splits_user    ON expense_splits(user_id);
+
+CREATE TABLE IF NOT EXISTS settlements (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id    UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+  from_user   UUID NOT NULL REFERENCES users(id),
+  to_user     UUID NOT NULL REFERENCES users(id),
+  amount      BIGINT NOT NULL,
+  method      TEXT   NOT NULL DEFAULT 'upi',   -- upi|cash|...
+  ref         TEXT,                            -- UTR / bank reference
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlements_group ON settlements(group_id);
+CREATE INDEX IF NOT EXISTS idx_users_upi_vpa     ON users(lower(upi_vpa));
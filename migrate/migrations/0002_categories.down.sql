@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS group_categories;
+DROP INDEX IF EXISTS idx_expenses_group_category;
+ALTER TABLE expenses DROP COLUMN IF EXISTS merchant_vpa;
+ALTER TABLE expenses DROP COLUMN IF EXISTS category;
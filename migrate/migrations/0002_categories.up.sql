@@ -0,0 +1,12 @@
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS category     TEXT NOT NULL DEFAULT 'other';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS merchant_vpa TEXT;
+CREATE INDEX IF NOT EXISTS idx_expenses_group_category ON expenses(group_id, category);
+
+-- per-group custom categories on top of the built-in list
+CREATE TABLE IF NOT EXISTS group_categories (
+  group_id   UUID REFERENCES groups(id) ON DELETE CASCADE,
+  name       TEXT NOT NULL,
+  keywords   TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (group_id, name)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_expenses_paid_by;
+DROP INDEX IF EXISTS idx_expenses_note_tsv;
+DROP INDEX IF EXISTS idx_expenses_group_created;
+ALTER TABLE expenses DROP COLUMN IF EXISTS note_tsv;
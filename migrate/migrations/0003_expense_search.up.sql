@@ -0,0 +1,6 @@
+-- expense search: keyset pagination + full-text on note
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS note_tsv tsvector
+  GENERATED ALWAYS AS (to_tsvector('simple', coalesce(note, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_expenses_group_created ON expenses(group_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_expenses_note_tsv      ON expenses USING GIN(note_tsv);
+CREATE INDEX IF NOT EXISTS idx_expenses_paid_by       ON expenses(paid_by);
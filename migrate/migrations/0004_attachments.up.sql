@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS expense_attachments (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  expense_id    UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+  file_name     TEXT   NOT NULL,
+  content_type  TEXT   NOT NULL,
+  size_bytes    BIGINT NOT NULL,
+  storage_key   TEXT   NOT NULL,
+  thumbnail_key TEXT,
+  uploaded_by   UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_expense ON expense_attachments(expense_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS group_activity;
+DROP TABLE IF EXISTS comments;
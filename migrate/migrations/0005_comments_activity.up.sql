@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS comments (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id    UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+  target_type TEXT NOT NULL CHECK (target_type IN ('expense', 'settlement')),
+  target_id   UUID NOT NULL,
+  author_id   UUID REFERENCES users(id) ON DELETE SET NULL,
+  body        TEXT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_target ON comments(target_type, target_id, created_at);
+
+-- group feed: who did what, newest first
+CREATE TABLE IF NOT EXISTS group_activity (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id    UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+  actor_id    UUID,
+  action      TEXT NOT NULL,
+  target_type TEXT,
+  target_id   TEXT,
+  data        JSONB,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_group ON group_activity(group_id, created_at DESC, id DESC);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS ledger_accounts;
+DROP TABLE IF EXISTS ledger_postings;
+DROP TABLE IF EXISTS ledger_journals;
+DROP FUNCTION IF EXISTS ledger_journal_balanced();
+DROP FUNCTION IF EXISTS ledger_immutable();
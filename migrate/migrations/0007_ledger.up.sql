@@ -0,0 +1,103 @@
+-- double-entry ledger: balances come from here, expenses/settlements only
+-- post to it. Journals and postings are append-only; a change posts a new
+-- journal with the difference.
+CREATE TABLE IF NOT EXISTS ledger_journals (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  group_id    UUID NOT NULL,
+  source_type TEXT NOT NULL CHECK (source_type IN ('expense', 'settlement')),
+  source_id   UUID NOT NULL,
+  memo        TEXT,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_journals_source ON ledger_journals(source_type, source_id);
+CREATE INDEX IF NOT EXISTS idx_journals_group ON ledger_journals(group_id, created_at);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  journal_id  UUID NOT NULL REFERENCES ledger_journals(id),
+  group_id    UUID NOT NULL,
+  user_id     UUID NOT NULL,
+  amount      BIGINT NOT NULL CHECK (amount <> 0) -- paise, credit > 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_postings_journal ON ledger_postings(journal_id);
+CREATE INDEX IF NOT EXISTS idx_postings_account ON ledger_postings(group_id, user_id);
+
+-- running totals, updated in the same tx as the postings
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+  group_id    UUID NOT NULL,
+  user_id     UUID NOT NULL,
+  balance     BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (group_id, user_id)
+);
+
+CREATE OR REPLACE FUNCTION ledger_immutable() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_journals_no_change ON ledger_journals;
+CREATE TRIGGER ledger_journals_no_change
+  BEFORE UPDATE OR DELETE ON ledger_journals
+  FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+
+DROP TRIGGER IF EXISTS ledger_postings_no_change ON ledger_postings;
+CREATE TRIGGER ledger_postings_no_change
+  BEFORE UPDATE OR DELETE ON ledger_postings
+  FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+
+-- checked at commit so a journal's postings can be inserted one by one
+CREATE OR REPLACE FUNCTION ledger_journal_balanced() RETURNS trigger AS $$
+BEGIN
+  IF (SELECT COALESCE(SUM(amount), 0) FROM ledger_postings WHERE journal_id = NEW.journal_id) <> 0 THEN
+    RAISE EXCEPTION 'ledger journal % does not balance', NEW.journal_id;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_postings_balanced ON ledger_postings;
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced
+  AFTER INSERT ON ledger_postings
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE FUNCTION ledger_journal_balanced();
+
+-- backfill expenses/settlements recorded before the ledger existed
+INSERT INTO ledger_journals (group_id, source_type, source_id, memo, created_at)
+SELECT e.group_id, 'expense', e.id, 'backfill', e.created_at
+FROM expenses e
+WHERE NOT EXISTS (SELECT 1 FROM ledger_journals j WHERE j.source_type = 'expense' AND j.source_id = e.id);
+
+INSERT INTO ledger_journals (group_id, source_type, source_id, memo, created_at)
+SELECT s.group_id, 'settlement', s.id, 'backfill', s.created_at
+FROM settlements s
+WHERE NOT EXISTS (SELECT 1 FROM ledger_journals j WHERE j.source_type = 'settlement' AND j.source_id = s.id);
+
+INSERT INTO ledger_postings (journal_id, group_id, user_id, amount)
+SELECT j.id, j.group_id, x.user_id, SUM(x.amount)
+FROM ledger_journals j
+JOIN (
+  SELECT e.id AS source_id, e.paid_by AS user_id, sp.exact AS amount
+  FROM expenses e JOIN expense_splits sp ON sp.expense_id = e.id
+  UNION ALL
+  SELECT sp.expense_id, sp.user_id, -sp.exact
+  FROM expense_splits sp
+) x ON x.source_id = j.source_id
+WHERE j.source_type = 'expense' AND j.memo = 'backfill'
+  AND NOT EXISTS (SELECT 1 FROM ledger_postings p WHERE p.journal_id = j.id)
+GROUP BY j.id, j.group_id, x.user_id
+HAVING SUM(x.amount) <> 0;
+
+INSERT INTO ledger_postings (journal_id, group_id, user_id, amount)
+SELECT j.id, j.group_id, x.user_id, x.amount
+FROM ledger_journals j
+JOIN settlements s ON s.id = j.source_id
+CROSS JOIN LATERAL (VALUES (s.from_user, s.amount), (s.to_user, -s.amount)) AS x(user_id, amount)
+WHERE j.source_type = 'settlement' AND j.memo = 'backfill'
+  AND NOT EXISTS (SELECT 1 FROM ledger_postings p WHERE p.journal_id = j.id);
+
+INSERT INTO ledger_accounts (group_id, user_id, balance)
+SELECT group_id, user_id, SUM(amount) FROM ledger_postings GROUP BY group_id, user_id
+ON CONFLICT (group_id, user_id) DO UPDATE SET balance = EXCLUDED.balance;
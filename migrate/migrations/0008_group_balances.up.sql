@@ -0,0 +1,11 @@
+-- one row per group, bumped in the same tx as every ledger post; the
+-- balance cache compares against version to know it is current
+CREATE TABLE IF NOT EXISTS group_balances (
+  group_id    UUID PRIMARY KEY,
+  version     BIGINT NOT NULL DEFAULT 0,
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO group_balances (group_id, version)
+SELECT DISTINCT group_id, 1 FROM ledger_accounts
+ON CONFLICT (group_id) DO NOTHING;
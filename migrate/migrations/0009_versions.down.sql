@@ -0,0 +1,3 @@
+ALTER TABLE expenses DROP COLUMN IF EXISTS version;
+ALTER TABLE groups   DROP COLUMN IF EXISTS version;
+ALTER TABLE users    DROP COLUMN IF EXISTS version;
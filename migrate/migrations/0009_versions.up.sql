@@ -0,0 +1,4 @@
+-- optimistic concurrency: every update bumps version, clients send it back in If-Match
+ALTER TABLE users    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE groups   ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,52 @@
+package rediscli
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/akarshgo/paysplit/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// metricsHook times every command into paysplit_redis_command_duration_seconds.
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := next(ctx, network, addr)
+		observe("dial", start, err)
+		return conn, err
+	}
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		observe(cmd.Name(), start, err)
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		observe("pipeline", start, err)
+		return err
+	}
+}
+
+func observe(command string, start time.Time, err error) {
+	result := "ok"
+	switch {
+	case errors.Is(err, redis.Nil):
+		result = "miss"
+	case err != nil:
+		result = "error"
+	}
+	metrics.RedisDuration.WithLabelValues(command, result).Observe(time.Since(start).Seconds())
+}
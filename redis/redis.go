@@ -2,30 +2,34 @@ package rediscli
 
 import (
 	"context"
-	"log"
-	"os"
-	"time"
+	"fmt"
 
+	"github.com/akarshgo/paysplit/config"
 	"github.com/redis/go-redis/v9"
 )
 
-var Rdb *redis.Client
-
-func Init() {
-	addr := os.Getenv("REDIS_ADDR")
-	if addr == "" {
-		addr = "localhost:6379"
-	}
-
-	Rdb = redis.NewClient(&redis.Options{
-		Addr:        addr,
-		Password:    "", // set via REDIS_PASSWORD if needed
-		DB:          0,
-		DialTimeout: 5 * time.Second,
+// New returns a client without talking to Redis; it connects lazily and
+// keeps retrying, so a Redis that comes up later is picked up on its own.
+// Every command is timed into the metrics and traced.
+func New(cfg config.Redis) *redis.Client {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:        cfg.Addr,
+		Username:    cfg.Username,
+		Password:    cfg.Password,
+		DB:          cfg.DB,
+		DialTimeout: cfg.DialTimeout,
 	})
+	rdb.AddHook(metricsHook{})
+	rdb.AddHook(tracingHook{})
+	return rdb
+}
 
-	ctx := context.Background()
-	if err := Rdb.Ping(ctx).Err(); err != nil {
-		log.Fatalf("redis: ping failed: %v", err)
+// Ping checks Redis answers within the dial timeout.
+func Ping(ctx context.Context, rdb *redis.Client, cfg config.Redis) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.DialTimeout)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis: ping %s: %w", cfg.Addr, err)
 	}
+	return nil
 }
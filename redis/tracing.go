@@ -0,0 +1,61 @@
+package rediscli
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/akarshgo/paysplit/redis")
+
+// tracingHook makes a client span per command or pipeline. Keys and values
+// stay out of the span: keys carry user IDs and Idempotency-Keys.
+type tracingHook struct{}
+
+func (tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := startSpan(ctx, "dial")
+		conn, err := next(ctx, network, addr)
+		endSpan(span, err)
+		return conn, err
+	}
+}
+
+func (tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := startSpan(ctx, cmd.Name())
+		err := next(ctx, cmd)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func (tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := startSpan(ctx, "pipeline")
+		err := next(ctx, cmds)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func startSpan(ctx context.Context, op string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "redis "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationName(op)),
+	)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
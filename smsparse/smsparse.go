@@ -0,0 +1,209 @@
+// Package smsparse extracts UPI / bank transaction details from the SMS and
+// notification text Indian banks send, e.g.
+//
+//	Rs.450.00 debited from A/c XX1234 to VPA swiggy@icici on 12-01-24. UPI Ref 412345678901
+//
+// Bank formats change often, so instead of one regex per template we pull
+// out each field (amount, direction, VPA, account, date, ref, merchant)
+// with a handful of patterns that cover HDFC, SBI, ICICI, Axis, Kotak and
+// the common UPI apps.
+package smsparse
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+type Direction string
+
+const (
+	Debit  Direction = "debit"
+	Credit Direction = "credit"
+)
+
+type Parsed struct {
+	Direction   Direction   `json:"direction"`
+	AmountPaise types.Money `json:"amount_paise"`
+	VPA         string      `json:"vpa,omitempty"` // counterparty, lower-cased
+	Merchant    string      `json:"merchant,omitempty"`
+	Account     string      `json:"account,omitempty"` // last digits only
+	Ref         string      `json:"ref,omitempty"`     // UPI ref / RRN
+	At          time.Time   `json:"at"`
+}
+
+var (
+	ErrNoAmount    = errors.New("smsparse: no amount found")
+	ErrNoDirection = errors.New("smsparse: can't tell if money was debited or credited")
+)
+
+const (
+	amountNum = `[0-9]+(?:,[0-9]+)*(?:\.[0-9]{1,2})?`
+	txnVerbs  = `(?:debited|credited|sent|paid|spent|received|deposited|withdrawn|transferred)`
+)
+
+var (
+	amountRe  = regexp.MustCompile(`(?i)(?:\brs\.?|\binr|₹)\s*(` + amountNum + `)`)
+	balanceRe = regexp.MustCompile(`(?i)\b(?:bal|balance|limit)\b(?:\s*(?:is|of))?[\s.:-]*$`)
+	debitRe   = regexp.MustCompile(`(?i)\b(debited|sent|paid|spent|withdrawn|transferred|trf to)\b`)
+	creditRe  = regexp.MustCompile(`(?i)\b(credited|received|deposited)\b`)
+	accountRe = regexp.MustCompile(`(?i)\b(?:a/c|acct|ac|account)\.?\s*(?:no\.?\s*)?[x*]*\s*(\d{3,6})\b`)
+	refRe     = regexp.MustCompile(`(?i)(?:upi\s*ref(?:\.|\s*no\.?)?|ref\s*no\.?|refno|ref|rrn|upi)\s*[:\-]?\s*(\d{6,})`)
+	blankRe   = regexp.MustCompile(`[ \t\r]+`)
+	upiInfoRe = regexp.MustCompile(`(?i)UPI/(?:P2[AM]/)?(\d{6,})/([A-Za-z0-9 .&'_-]+)`)
+
+	// the amount next to the debited/credited phrase, on either side; bare
+	// numbers only after "debited by" and the like
+	txnAmountRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b` + txnVerbs + `\s+(?:(?:by|with|for|of)\s+)?(?:rs\.?|inr|₹)\s*(` + amountNum + `)`),
+		regexp.MustCompile(`(?i)\b(?:debited|credited)\s+(?:by|with|for)\s+(` + amountNum + `)`),
+		regexp.MustCompile(`(?i)(?:\brs\.?|\binr|₹)\s*(` + amountNum + `)\s+(?:(?:has been|is|was)\s+)?` + txnVerbs + `\b`),
+	}
+
+	// merchant names the banks spell out next to the amount
+	merchantRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)trf to\s+([A-Za-z0-9 .&'_-]+?)\s+(?:ref|on|upi)`),
+		regexp.MustCompile(`(?i);\s*([A-Za-z0-9 .&'_-]+?)\s+credited`),
+		regexp.MustCompile(`(?i)\bat\s+([A-Za-z0-9 .&'_-]+?)\s+on\b`),
+		regexp.MustCompile(`(?i)\bto\s+([A-Za-z][A-Za-z0-9 .&'_-]+?)\s+(?:on|via|ref|upi|from)\b`),
+		regexp.MustCompile(`(?i)\bfrom\s+([A-Za-z][A-Za-z0-9 .&'_-]+?)\s+(?:on|via|ref|upi)\b`),
+	}
+)
+
+// dates with optional time, day first
+var dateRes = []struct {
+	re      *regexp.Regexp
+	layouts []string
+}{
+	{regexp.MustCompile(`\b(\d{1,2}[-/]\d{1,2}[-/]\d{2,4})(?:[ ,]+(\d{1,2}:\d{2}(?::\d{2})?))?`),
+		[]string{"2-1-2006", "2-1-06", "2/1/2006", "2/1/06"}},
+	{regexp.MustCompile(`(?i)\b(\d{1,2}[- ]?(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*[- ]?\d{2,4})(?:[ ,]+(\d{1,2}:\d{2}(?::\d{2})?))?`),
+		[]string{"2-Jan-2006", "2-Jan-06", "2 Jan 2006", "2 Jan 06", "2Jan2006", "2Jan06", "2-January-2006", "2 January 2006"}},
+	{regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})(?:[ T]+(\d{1,2}:\d{2}(?::\d{2})?))?`),
+		[]string{"2006-01-02"}},
+}
+
+// Parse extracts what it can from text. received is used as the timestamp
+// when the message has no date of its own.
+func Parse(text string, received time.Time) (*Parsed, error) {
+	// keep line breaks: Axis puts "UPI/P2M/<ref>/<merchant>" on its own line
+	text = strings.TrimSpace(blankRe.ReplaceAllString(text, " "))
+
+	p := &Parsed{At: received}
+
+	amt, ok := amount(text)
+	if !ok {
+		return nil, ErrNoAmount
+	}
+	paise, err := types.ParseMoney(amt)
+	if err != nil || paise <= 0 {
+		return nil, ErrNoAmount
+	}
+	p.AmountPaise = paise
+
+	// whichever keyword comes first wins: "debited ...; SWIGGY credited" is a debit
+	d, c := debitRe.FindStringIndex(text), creditRe.FindStringIndex(text)
+	switch {
+	case d != nil && (c == nil || d[0] < c[0]):
+		p.Direction = Debit
+	case c != nil:
+		p.Direction = Credit
+	default:
+		return nil, ErrNoDirection
+	}
+
+	p.VPA = types.ExtractVPA(text)
+	if m := accountRe.FindStringSubmatch(text); m != nil {
+		p.Account = m[1]
+	}
+	if m := refRe.FindStringSubmatch(text); m != nil {
+		p.Ref = m[1]
+	}
+	if m := upiInfoRe.FindStringSubmatch(text); m != nil {
+		if p.Ref == "" {
+			p.Ref = m[1]
+		}
+		// the name runs to the end of the line or sentence: "/SWIGGY. Avl Bal"
+		name, _, _ := strings.Cut(m[2], ". ")
+		p.Merchant = strings.TrimRight(strings.TrimSpace(name), " .")
+	}
+	if p.Merchant == "" {
+		p.Merchant = merchant(text, p.VPA)
+	}
+	if t, ok := parseDate(text); ok {
+		p.At = t
+	}
+	return p, nil
+}
+
+// amount is the one next to the debited/credited phrase, or failing that the
+// first one that isn't the balance or a limit ("Avl Bal Rs 12,000").
+func amount(text string) (string, bool) {
+	at, amt := -1, ""
+	for _, re := range txnAmountRes {
+		if m := re.FindStringSubmatchIndex(text); m != nil && (at < 0 || m[0] < at) {
+			at, amt = m[0], text[m[2]:m[3]]
+		}
+	}
+	if at >= 0 {
+		return amt, true
+	}
+	for _, m := range amountRe.FindAllStringSubmatchIndex(text, -1) {
+		if !balanceRe.MatchString(text[:m[0]]) {
+			return text[m[2]:m[3]], true
+		}
+	}
+	return "", false
+}
+
+func merchant(text, vpa string) string {
+	for _, re := range merchantRes {
+		m := re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		// "from BALA. UPI:..." leaves the full stop on
+		name := strings.TrimRight(strings.TrimSpace(m[1]), " .-")
+		low := strings.ToLower(name)
+		// "to VPA x@y", "from HDFC Bank A/C" are not merchant names
+		if low == "" || strings.HasPrefix(low, "vpa") || strings.Contains(low, "a/c") ||
+			strings.Contains(low, "bank") || strings.Contains(name, "@") {
+			continue
+		}
+		return name
+	}
+	if vpa != "" {
+		handle, _, _ := strings.Cut(vpa, "@")
+		return handle
+	}
+	return ""
+}
+
+func parseDate(text string) (time.Time, bool) {
+	for _, d := range dateRes {
+		m := d.re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		for _, l := range d.layouts {
+			day, err := time.ParseInLocation(l, m[1], types.IST)
+			if err != nil {
+				continue
+			}
+			if m[2] != "" {
+				clock := m[2]
+				if strings.Count(clock, ":") == 1 {
+					clock += ":00"
+				}
+				if t, err := time.ParseInLocation("15:04:05", clock, types.IST); err == nil {
+					day = day.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second)
+				}
+			}
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
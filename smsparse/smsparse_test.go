@@ -0,0 +1,145 @@
+package smsparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+func TestParse(t *testing.T) {
+	received := time.Date(2024, 1, 12, 20, 0, 0, 0, types.IST)
+	on := func(h, m, s int) time.Time { return time.Date(2024, 1, 12, h, m, s, 0, types.IST) }
+
+	tests := []struct {
+		name string
+		text string
+		want Parsed
+	}{
+		{
+			name: "HDFC debit to a VPA",
+			text: "Rs.450.00 debited from a/c **1234 on 12-01-24 to VPA swiggy@icici(UPI Ref No 412345678901). Not you? Call on 18002586161 to report",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, VPA: "swiggy@icici", Merchant: "swiggy", Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "HDFC money sent, one field per line",
+			text: "Sent Rs.450.00\nFrom HDFC Bank A/C *1234\nTo SWIGGY\nOn 12/01/24\nRef 412345678901\nNot You?\nCall 18002586161/SMS BLOCK UPI to 7308080808",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Merchant: "SWIGGY", Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "HDFC deposit with the balance after",
+			text: "Update! INR 1,500.00 deposited in HDFC Bank A/c XX1234 on 12-JAN-24 for UPI-412345678901-bala@okaxis. Avl bal INR 25,000.00",
+			want: Parsed{Direction: Credit, AmountPaise: 150000, VPA: "bala@okaxis", Merchant: "bala", Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "SBI debit with a bare amount",
+			text: "Dear UPI user A/C X1234 debited by 450.0 on date 12Jan24 trf to SWIGGY Refno 412345678901. If not u? call 1800111109. -SBI",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Merchant: "SWIGGY", Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "SBI credit",
+			text: "Dear SBI UPI User, ur A/cX1234 credited by Rs500 on 12Jan24 by  (Ref no 412345678901)",
+			want: Parsed{Direction: Credit, AmountPaise: 50000, Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "ICICI debit naming the payee",
+			text: "ICICI Bank Acct XX123 debited for Rs 450.00 on 12-Jan-24; SWIGGY credited. UPI:412345678901. Call 18002662 for dispute. SMS BLOCK 123 to 9215676766",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Merchant: "SWIGGY", Account: "123", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "ICICI credit",
+			text: "Dear Customer, Acct XX123 is credited with Rs 500.00 on 12-Jan-24 from BALA. UPI:412345678901-ICICI Bank.",
+			want: Parsed{Direction: Credit, AmountPaise: 50000, Merchant: "BALA", Account: "123", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "Axis debit, UPI details on their own line",
+			text: "INR 450.00 debited\nA/c no. XX1234\n12-01-24, 14:23:05\nUPI/P2M/412345678901/SWIGGY\nNot you? SMS BLOCKUPI Cust ID to 919951860002\nAxis Bank",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Merchant: "SWIGGY", Account: "1234", Ref: "412345678901", At: on(14, 23, 5)},
+		},
+		{
+			name: "Axis credit",
+			text: "INR 500.00 credited\nA/c no. XX1234\n12-01-24, 09:15:00 IST\nUPI/P2A/412345678901/BALA\nNot you? SMS BLOCKUPI Cust ID to 919951860002\nAxis Bank",
+			want: Parsed{Direction: Credit, AmountPaise: 50000, Merchant: "BALA", Account: "1234", Ref: "412345678901", At: on(9, 15, 0)},
+		},
+		{
+			name: "Axis debit with the balance after",
+			text: "Your A/c XX1234 has been debited with INR 450.00 on 12-01-24 towards UPI/P2M/412345678901/SWIGGY. Avl Bal INR 12,345.67",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Merchant: "SWIGGY", Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "Kotak sent",
+			text: "Sent Rs.450.00 from Kotak Bank AC X1234 to swiggy@icici on 12-01-24.UPI Ref 412345678901. Not you, https://kotak.com/KBANKT/Fraud",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, VPA: "swiggy@icici", Merchant: "swiggy", Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "Kotak received",
+			text: "Received Rs.500.00 in your Kotak Bank AC X1234 from bala@okaxis on 12-01-24.UPI Ref:412345678901.",
+			want: Parsed{Direction: Credit, AmountPaise: 50000, VPA: "bala@okaxis", Merchant: "bala", Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "balance before the amount",
+			text: "Avl Bal Rs 12,345.67. Rs 450.00 debited from A/c XX1234 on 12-01-24 UPI Ref 412345678901",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "comma after the amount",
+			text: "Rs.1,250, debited from A/c XX1234 on 12-01-24. UPI Ref 412345678901",
+			want: Parsed{Direction: Debit, AmountPaise: 125000, Account: "1234", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "Google Pay",
+			text: "₹450.00 paid to Swiggy via Google Pay. UPI Ref 412345678901",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Merchant: "Swiggy", Ref: "412345678901", At: received},
+		},
+		{
+			name: "PhonePe",
+			text: "Paid ₹450 to SWIGGY on 12 Jan 2024. UPI Ref: 412345678901",
+			want: Parsed{Direction: Debit, AmountPaise: 45000, Merchant: "SWIGGY", Ref: "412345678901", At: on(0, 0, 0)},
+		},
+		{
+			name: "Paytm",
+			text: "Rs.1,00,000 sent to Bala Kumar from Paytm UPI. UPI Ref: 412345678901",
+			want: Parsed{Direction: Debit, AmountPaise: 10000000, Merchant: "Bala Kumar", Ref: "412345678901", At: received},
+		},
+		{
+			name: "BHIM received",
+			text: "Received ₹500.00 from bala@okaxis. UPI Ref No 412345678901",
+			want: Parsed{Direction: Credit, AmountPaise: 50000, VPA: "bala@okaxis", Merchant: "bala", Ref: "412345678901", At: received},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.text, received)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.At.Equal(tt.want.At) {
+				t.Errorf("At = %v, want %v", got.At, tt.want.At)
+			}
+			got.At, tt.want.At = time.Time{}, time.Time{}
+			if *got != tt.want {
+				t.Errorf("got  %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want error
+	}{
+		{name: "OTP", text: "123456 is your OTP for txn of INR 450.00 at SWIGGY. Valid for 10 mins. Do not share", want: ErrNoDirection},
+		{name: "no amount", text: "Your A/c XX1234 has been debited. Call 18002586161", want: ErrNoAmount},
+		{name: "zero", text: "Rs.0.00 debited from A/c XX1234", want: ErrNoAmount},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.text, time.Now()); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
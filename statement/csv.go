@@ -0,0 +1,154 @@
+package statement
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+// column indexes resolved from the header row; -1 means absent
+type csvColumns struct {
+	date, narration, debit, credit, amount, kind, ref, vpa int
+}
+
+// header aliases seen in HDFC/ICICI/SBI/Axis exports and GPay/PhonePe/Paytm history.
+// Keys are normalised (lower-case, letters only).
+var csvAliases = map[string][]string{
+	"date":      {"txndate", "transactiondate", "trandate", "date", "valuedate", "valuedt"},
+	"narration": {"narration", "description", "particulars", "transactionremarks", "transactiondetails", "remarks", "details"},
+	"debit":     {"withdrawalamt", "withdrawalamount", "withdrawalamountinr", "withdrawal", "debitamount", "debit", "dr"},
+	"credit":    {"depositamt", "depositamount", "depositamountinr", "deposit", "creditamount", "credit", "cr"},
+	"amount":    {"amount", "amountinr", "transactionamount", "amt"},
+	"kind":      {"type", "drcr", "crdr", "transactiontype"},
+	"ref":       {"utr", "upirefno", "refno", "chqrefno", "refnochequeno", "reference", "transactionid", "referenceno"},
+	"vpa":       {"vpa", "upiid", "upivpa", "othertransactiondetailsupiidoracno"},
+}
+
+func normHeader(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func resolveColumns(header []string) (csvColumns, bool) {
+	norm := make([]string, len(header))
+	for i, h := range header {
+		norm[i] = normHeader(h)
+	}
+	find := func(key string) int {
+		// alias order is preference order, e.g. txn date before value date
+		for _, alias := range csvAliases[key] {
+			for i, h := range norm {
+				if h == alias {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	c := csvColumns{
+		date:      find("date"),
+		narration: find("narration"),
+		debit:     find("debit"),
+		credit:    find("credit"),
+		amount:    find("amount"),
+		kind:      find("kind"),
+		ref:       find("ref"),
+		vpa:       find("vpa"),
+	}
+	ok := c.date >= 0 && (c.amount >= 0 || c.debit >= 0 || c.credit >= 0)
+	return c, ok
+}
+
+// ParseCSV reads a bank or UPI app CSV export. Preamble lines before the
+// header (account number, period, ...) and footer lines are skipped.
+func ParseCSV(r io.Reader) ([]Txn, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+
+	var (
+		cols   csvColumns
+		header bool
+		out    []Txn
+	)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !header {
+			cols, header = resolveColumns(rec)
+			continue
+		}
+		if t, ok := cols.txn(rec); ok {
+			out = append(out, t)
+		}
+	}
+	if !header {
+		return nil, errors.New("statement: no recognisable header row in CSV")
+	}
+	return out, nil
+}
+
+func (c csvColumns) txn(rec []string) (Txn, bool) {
+	get := func(i int) string {
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	date, err := parseDate(get(c.date))
+	if err != nil {
+		return Txn{}, false // footer / summary rows
+	}
+	t := Txn{Date: date, Narration: get(c.narration), Ref: get(c.ref)}
+
+	switch {
+	case get(c.debit) != "" || get(c.credit) != "":
+		if p, _, err := parseAmount(get(c.debit)); err == nil && p > 0 {
+			t.Direction, t.AmountPaise = Debit, p
+		} else if p, _, err := parseAmount(get(c.credit)); err == nil && p > 0 {
+			t.Direction, t.AmountPaise = Credit, p
+		}
+	case get(c.amount) != "":
+		p, neg, err := parseAmount(get(c.amount))
+		if err != nil {
+			return Txn{}, false
+		}
+		t.AmountPaise = p
+		t.Direction = Credit
+		if neg {
+			t.Direction = Debit
+		}
+		switch k := strings.ToLower(get(c.kind)); {
+		case k == "":
+		case strings.HasPrefix(k, "dr"), strings.HasPrefix(k, "debit"), k == "paid", k == "sent":
+			t.Direction = Debit
+		case strings.HasPrefix(k, "cr"), strings.HasPrefix(k, "credit"), k == "received":
+			t.Direction = Credit
+		}
+	}
+	if t.AmountPaise <= 0 {
+		return Txn{}, false
+	}
+
+	// Paytm puts the account number there when the payee isn't on UPI
+	t.VPA = types.ExtractVPA(get(c.vpa))
+	if t.VPA == "" {
+		t.VPA = types.ExtractVPA(t.Narration)
+	}
+	return t, true
+}
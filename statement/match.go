@@ -0,0 +1,97 @@
+package statement
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+// Debt is one outstanding transfer from the simplified group balances.
+type Debt struct {
+	GroupID     string
+	From        string
+	To          string
+	AmountPaise types.Money
+}
+
+// Suggestion is a settlement we think a statement line represents.
+// Partial is set when the payment covers only part of the debt.
+type Suggestion struct {
+	GroupID     string      `json:"group_id"`
+	FromUser    string      `json:"from_user"`
+	ToUser      string      `json:"to_user"`
+	AmountPaise types.Money `json:"amount_paise"`
+	Partial     bool        `json:"partial"`
+	Txn         Txn         `json:"txn"`
+}
+
+type MatchOptions struct {
+	Since     time.Time       // ignore statement lines older than this
+	KnownRefs map[string]bool // refs already recorded as settlements
+}
+
+// Match pairs the uploader's statement lines with outstanding debts.
+// vpaOwner maps a lower-cased VPA to the paysplit user that owns it.
+//
+// A debit to a known VPA settles a debt userID owes that person, a credit
+// from one settles a debt they owe userID. Exact amounts win over partial
+// payments, and each debt is only consumed up to its outstanding amount.
+func Match(userID string, txns []Txn, debts []Debt, vpaOwner map[string]string, opt MatchOptions) []Suggestion {
+	remaining := make([]types.Money, len(debts))
+	for i, d := range debts {
+		remaining[i] = d.AmountPaise
+	}
+
+	// oldest first so earlier payments are applied first
+	sorted := append([]Txn(nil), txns...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	var out []Suggestion
+	for _, t := range sorted {
+		if t.VPA == "" || t.Date.Before(opt.Since) {
+			continue
+		}
+		if t.Ref != "" && opt.KnownRefs[t.Ref] {
+			continue
+		}
+		other, ok := vpaOwner[strings.ToLower(t.VPA)]
+		if !ok || other == userID {
+			continue
+		}
+		from, to := userID, other
+		if t.Direction == Credit {
+			from, to = other, userID
+		}
+
+		best := -1
+		for i, d := range debts {
+			if d.From != from || d.To != to || remaining[i] == 0 {
+				continue
+			}
+			if remaining[i] == t.AmountPaise {
+				best = i
+				break
+			}
+			if best < 0 && remaining[i] > t.AmountPaise {
+				best = i
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		d := debts[best]
+		out = append(out, Suggestion{
+			GroupID:     d.GroupID,
+			FromUser:    from,
+			ToUser:      to,
+			AmountPaise: t.AmountPaise,
+			Partial:     remaining[best] != t.AmountPaise,
+			Txn:         t,
+		})
+		remaining[best] -= t.AmountPaise
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package statement
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+var ofxTagRe = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+
+// ParseOFX reads the transaction list out of an OFX/QFX file. Both the old
+// SGML flavour (unclosed tags) and OFX 2.x XML are handled; we only care
+// about STMTTRN blocks so nothing else is validated.
+func ParseOFX(r io.Reader) ([]Txn, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body := string(raw)
+	if !strings.Contains(strings.ToUpper(body), "<OFX>") {
+		return nil, errors.New("statement: not an OFX document")
+	}
+
+	var out []Txn
+	for _, block := range ofxBlocks(body) {
+		fields := map[string]string{}
+		for _, f := range ofxTagRe.FindAllStringSubmatch(block, -1) {
+			fields[strings.ToUpper(f[1])] = strings.TrimSpace(f[2])
+		}
+
+		date, err := parseOFXDate(fields["DTPOSTED"])
+		if err != nil {
+			continue
+		}
+		paise, neg, err := parseAmount(fields["TRNAMT"])
+		if err != nil || paise == 0 {
+			continue
+		}
+		t := Txn{
+			Date:        date,
+			AmountPaise: paise,
+			Direction:   Credit,
+			Ref:         fields["FITID"],
+			Narration:   strings.TrimSpace(fields["NAME"] + " " + fields["MEMO"]),
+		}
+		if neg || strings.EqualFold(fields["TRNTYPE"], "DEBIT") {
+			t.Direction = Debit
+		}
+		t.VPA = types.ExtractVPA(t.Narration)
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// ofxBlocks returns the inside of every <STMTTRN>. SGML files may omit the
+// closing tag, so a block also ends where the next one starts.
+// OFX tag names are upper-case by spec.
+func ofxBlocks(body string) []string {
+	var out []string
+	for {
+		i := strings.Index(body, "<STMTTRN>")
+		if i < 0 {
+			return out
+		}
+		body = body[i+len("<STMTTRN>"):]
+		end := len(body)
+		for _, term := range []string{"</STMTTRN>", "<STMTTRN>", "</BANKTRANLIST>"} {
+			if j := strings.Index(body, term); j >= 0 && j < end {
+				end = j
+			}
+		}
+		out = append(out, body[:end])
+	}
+}
+
+// OFX dates look like 20240112, 20240112093000 or 20240112093000.000[+5.30:IST]
+func parseOFXDate(s string) (time.Time, error) {
+	if i := strings.IndexAny(s, ".["); i >= 0 {
+		s = s[:i]
+	}
+	if len(s) >= 14 {
+		return time.ParseInLocation("20060102150405", s[:14], types.IST)
+	}
+	if len(s) >= 8 {
+		return time.ParseInLocation("20060102", s[:8], types.IST)
+	}
+	return time.Time{}, errors.New("statement: bad OFX date")
+}
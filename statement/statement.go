@@ -0,0 +1,100 @@
+// Package statement parses bank / UPI app statement exports and matches the
+// transactions in them against outstanding balances inside paysplit.
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+type Direction string
+
+const (
+	Debit  Direction = "debit"  // money left the uploader's account
+	Credit Direction = "credit" // money came in
+)
+
+// Txn is one statement line, amounts always positive paise.
+type Txn struct {
+	Date        time.Time   `json:"date"`
+	Direction   Direction   `json:"direction"`
+	AmountPaise types.Money `json:"amount_paise"`
+	VPA         string      `json:"vpa,omitempty"` // counterparty, lower-cased
+	Ref         string      `json:"ref,omitempty"` // UTR / FITID
+	Narration   string      `json:"narration,omitempty"`
+}
+
+var ErrUnknownFormat = errors.New("statement: unknown format")
+
+// Parse reads a statement in the given format ("csv" or "ofx").
+func Parse(format string, r io.Reader) ([]Txn, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return ParseCSV(r)
+	case "ofx", "qfx":
+		return ParseOFX(r)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// FormatFromFilename guesses the format from an uploaded file name.
+func FormatFromFilename(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".ofx"), strings.HasSuffix(name, ".qfx"):
+		return "ofx"
+	case strings.HasSuffix(name, ".csv"):
+		return "csv"
+	}
+	return ""
+}
+
+// parseAmount reads a statement amount. The sign (or accounting-style
+// parentheses) is returned separately so callers can use it as the direction.
+func parseAmount(s string) (m types.Money, negative bool, err error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s, negative = s[1:len(s)-1], true
+	}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "+")
+	m, err = types.ParseMoney(s)
+	if err != nil {
+		return 0, false, err
+	}
+	if m < 0 {
+		m, negative = -m, true
+	}
+	return m, negative, nil
+}
+
+var dateLayouts = []string{
+	"2006-01-02",
+	"02/01/2006",
+	"02/01/06",
+	"02-01-2006",
+	"02-01-06",
+	"02-Jan-2006",
+	"02-Jan-06",
+	"02 Jan 2006",
+	"Jan 02, 2006",
+	"02/01/2006 15:04:05",
+	"2006-01-02 15:04:05",
+	"02 Jan 2006 15:04",
+	"Jan 02, 2006 03:04 PM",
+}
+
+// parseDate tries the formats Indian banks/UPI apps commonly export (day first).
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, l := range dateLayouts {
+		if t, err := time.ParseInLocation(l, s, types.IST); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+}
@@ -0,0 +1,162 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akarshgo/paysplit/types"
+)
+
+func TestParseCSV(t *testing.T) {
+	jan12 := time.Date(2024, 1, 12, 0, 0, 0, 0, types.IST)
+
+	tests := []struct {
+		name string
+		csv  string
+		want []Txn
+	}{
+		{
+			name: "HDFC",
+			csv: "HDFC BANK Ltd.,,,,,,\n" +
+				"Statement From : 01/01/24 To : 31/01/24,,,,,,\n" +
+				"Date,Narration,Chq./Ref.No.,Value Dt,Withdrawal Amt.,Deposit Amt.,Closing Balance\n" +
+				"12/01/24,UPI-SWIGGY-swiggy@icici-ICIC0000001-412345678901-PAYMENT,0000412345678901,12/01/24,450.00,,\"24,550.00\"\n" +
+				"12/01/24,UPI-BALA K-bala@okaxis-UTIB0000001-412345678902-GOA TRIP,0000412345678902,12/01/24,,\"1,00,000.00\",\"1,24,550.00\"\n" +
+				"STATEMENT SUMMARY :-,,,,,,\n",
+			want: []Txn{
+				{Date: jan12, Direction: Debit, AmountPaise: 45000, VPA: "swiggy@icici", Ref: "0000412345678901", Narration: "UPI-SWIGGY-swiggy@icici-ICIC0000001-412345678901-PAYMENT"},
+				{Date: jan12, Direction: Credit, AmountPaise: 10000000, VPA: "bala@okaxis", Ref: "0000412345678902", Narration: "UPI-BALA K-bala@okaxis-UTIB0000001-412345678902-GOA TRIP"},
+			},
+		},
+		{
+			name: "SBI",
+			csv: "Account Name :,Mr. ASHA,,,,,\n" +
+				"Txn Date,Value Date,Description,Ref No./Cheque No.,Debit,Credit,Balance\n" +
+				"12 Jan 2024,12 Jan 2024,TO TRANSFER-UPI/DR/412345678901/SWIGGY/YESB/swiggy@ybl/Payment--,TRANSFER TO 4897694162092,450.00,,\"24,550.00\"\n" +
+				"12 Jan 2024,12 Jan 2024,BY TRANSFER-UPI/CR/412345678902/BALA/UTIB/bala@okaxis/UPI--,TRANSFER FROM 4897691162091,,500.00,\"25,050.00\"\n",
+			want: []Txn{
+				{Date: jan12, Direction: Debit, AmountPaise: 45000, VPA: "swiggy@ybl", Ref: "TRANSFER TO 4897694162092", Narration: "TO TRANSFER-UPI/DR/412345678901/SWIGGY/YESB/swiggy@ybl/Payment--"},
+				{Date: jan12, Direction: Credit, AmountPaise: 50000, VPA: "bala@okaxis", Ref: "TRANSFER FROM 4897691162091", Narration: "BY TRANSFER-UPI/CR/412345678902/BALA/UTIB/bala@okaxis/UPI--"},
+			},
+		},
+		{
+			name: "ICICI",
+			csv: "S No.,Value Date,Transaction Date,Cheque Number,Transaction Remarks,Withdrawal Amount (INR ),Deposit Amount (INR ),Balance (INR )\n" +
+				"1,12/01/2024,12/01/2024,-,UPI/412345678901/Payment from Ph/swiggy@icici/ICICI Bank,450.00,0.00,\"24,550.00\"\n" +
+				"2,12/01/2024,12/01/2024,-,UPI/412345678902/goa/bala@okaxis/Axis Bank,0.00,500.00,\"25,050.00\"\n",
+			want: []Txn{
+				{Date: jan12, Direction: Debit, AmountPaise: 45000, VPA: "swiggy@icici", Narration: "UPI/412345678901/Payment from Ph/swiggy@icici/ICICI Bank"},
+				{Date: jan12, Direction: Credit, AmountPaise: 50000, VPA: "bala@okaxis", Narration: "UPI/412345678902/goa/bala@okaxis/Axis Bank"},
+			},
+		},
+		{
+			name: "Axis",
+			csv: "Tran Date,CHQNO,PARTICULARS,DR,CR,BAL,SOL\n" +
+				"12-01-2024,,UPI/P2M/412345678901/SWIGGY/Payment fo/YESB,450.00,,24550.00,1234\n" +
+				"12-01-2024,,UPI/P2A/412345678902/BALA K/UPI/UTIB,,500.00,25050.00,1234\n" +
+				"TRANSACTION TOTAL,,,450.00,500.00,,\n",
+			want: []Txn{
+				{Date: jan12, Direction: Debit, AmountPaise: 45000, Narration: "UPI/P2M/412345678901/SWIGGY/Payment fo/YESB"},
+				{Date: jan12, Direction: Credit, AmountPaise: 50000, Narration: "UPI/P2A/412345678902/BALA K/UPI/UTIB"},
+			},
+		},
+		{
+			name: "Kotak, amount with a Dr/Cr column",
+			csv: "Sl. No.,Transaction Date,Value Date,Description,Chq / Ref No.,Amount,Dr / Cr,Balance,Dr / Cr\n" +
+				"1,12-01-2024,12-01-2024,UPI/swiggy@icici/412345678901/Payment,UPI-412345678901,450.00,DR,\"24,550.00\",CR\n" +
+				"2,12-01-2024,12-01-2024,UPI/bala@okaxis/412345678902/goa,UPI-412345678902,500.00,CR,\"25,050.00\",CR\n",
+			want: []Txn{
+				{Date: jan12, Direction: Debit, AmountPaise: 45000, VPA: "swiggy@icici", Ref: "UPI-412345678901", Narration: "UPI/swiggy@icici/412345678901/Payment"},
+				{Date: jan12, Direction: Credit, AmountPaise: 50000, VPA: "bala@okaxis", Ref: "UPI-412345678902", Narration: "UPI/bala@okaxis/412345678902/goa"},
+			},
+		},
+		{
+			name: "PhonePe",
+			csv: "Date,Transaction Details,Type,Amount\n" +
+				"\"Jan 12, 2024 03:04 PM\",Paid to SWIGGY,Debit,₹450\n" +
+				"\"Jan 12, 2024 09:30 AM\",Received from Bala K,Credit,₹500\n",
+			want: []Txn{
+				{Date: jan12.Add(15*time.Hour + 4*time.Minute), Direction: Debit, AmountPaise: 45000, Narration: "Paid to SWIGGY"},
+				{Date: jan12.Add(9*time.Hour + 30*time.Minute), Direction: Credit, AmountPaise: 50000, Narration: "Received from Bala K"},
+			},
+		},
+		{
+			name: "Paytm, signed amounts",
+			csv: "Date,Time,Transaction Details,Other Transaction Details (UPI ID or A/c No),Your Account,Amount,UPI Ref No.,Order ID,Remarks,Tags,Comment\n" +
+				"12/01/2024,15:04:05,Paid to Swiggy,swiggy@paytm,HDFC Bank - 34,-450.00,412345678901,,,#Food,\n" +
+				"12/01/2024,09:30:00,Received from Bala K,A/c XX1234,HDFC Bank - 34,+500.00,412345678902,,,,\n",
+			want: []Txn{
+				{Date: jan12, Direction: Debit, AmountPaise: 45000, VPA: "swiggy@paytm", Ref: "412345678901", Narration: "Paid to Swiggy"},
+				{Date: jan12, Direction: Credit, AmountPaise: 50000, Ref: "412345678902", Narration: "Received from Bala K"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse("csv", strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTxns(t, got, tt.want)
+		})
+	}
+}
+
+func TestParseCSVWithoutHeader(t *testing.T) {
+	if _, err := ParseCSV(strings.NewReader("foo,bar\n1,2\n")); err == nil {
+		t.Error("want an error for a CSV without a recognisable header")
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name string
+		ofx  string
+	}{
+		{
+			name: "SGML",
+			ofx: "OFXHEADER:100\nDATA:OFXSGML\nVERSION:102\n\n<OFX>\n<BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>\n" +
+				"<STMTTRN>\n<TRNTYPE>DEBIT\n<DTPOSTED>20240112150405.000[+5.30:IST]\n<TRNAMT>-450.00\n<FITID>412345678901\n<NAME>UPI-SWIGGY\n<MEMO>swiggy@icici\n" +
+				"<STMTTRN>\n<TRNTYPE>CREDIT\n<DTPOSTED>20240112\n<TRNAMT>500.00\n<FITID>412345678902\n<NAME>UPI-BALA K\n<MEMO>bala@okaxis goa\n" +
+				"</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n",
+		},
+		{
+			name: "XML",
+			ofx: `<?xml version="1.0" encoding="UTF-8"?><?OFX OFXHEADER="200" VERSION="220"?>` + "\n<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>\n" +
+				"<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240112150405</DTPOSTED><TRNAMT>-450.00</TRNAMT><FITID>412345678901</FITID><NAME>UPI-SWIGGY</NAME><MEMO>swiggy@icici</MEMO></STMTTRN>\n" +
+				"<STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20240112</DTPOSTED><TRNAMT>500.00</TRNAMT><FITID>412345678902</FITID><NAME>UPI-BALA K</NAME><MEMO>bala@okaxis goa</MEMO></STMTTRN>\n" +
+				"</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>\n",
+		},
+	}
+	want := []Txn{
+		{Date: time.Date(2024, 1, 12, 15, 4, 5, 0, types.IST), Direction: Debit, AmountPaise: 45000, VPA: "swiggy@icici", Ref: "412345678901", Narration: "UPI-SWIGGY swiggy@icici"},
+		{Date: time.Date(2024, 1, 12, 0, 0, 0, 0, types.IST), Direction: Credit, AmountPaise: 50000, VPA: "bala@okaxis", Ref: "412345678902", Narration: "UPI-BALA K bala@okaxis goa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse("ofx", strings.NewReader(tt.ofx))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTxns(t, got, want)
+		})
+	}
+}
+
+func checkTxns(t *testing.T, got, want []Txn) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d transactions, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if !g.Date.Equal(w.Date) {
+			t.Errorf("[%d] Date = %v, want %v", i, g.Date, w.Date)
+		}
+		g.Date, w.Date = time.Time{}, time.Time{}
+		if g != w {
+			t.Errorf("[%d] got  %+v\nwant %+v", i, g, w)
+		}
+	}
+}
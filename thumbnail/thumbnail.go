@@ -0,0 +1,65 @@
+// Package thumbnail makes small JPEG previews of uploaded receipt photos.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// MaxSide is the longest edge of a generated thumbnail, in pixels.
+const MaxSide = 320
+
+// MaxPixels is the largest image Make will decode: a 48 MP phone photo,
+// about 200 MB once decoded. The header is checked first, so a small file
+// claiming to be 50000x50000 never gets its pixels allocated.
+const MaxPixels = 50_000_000
+
+var ErrTooLarge = errors.New("thumbnail: image dimensions too large")
+
+// Make decodes a JPEG/PNG/GIF/WebP image and returns a JPEG no larger than
+// MaxSide on either edge. Images already that small are re-encoded as is.
+func Make(r io.Reader) ([]byte, error) {
+	// read the header, then replay it in front of the rest for the decode
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &head))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(io.MultiReader(&head, r))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > MaxSide || h > MaxSide {
+		if w >= h {
+			w, h = MaxSide, max(1, h*MaxSide/w)
+		} else {
+			w, h = max(1, w*MaxSide/h), MaxSide
+		}
+	}
+
+	// white background so transparent PNG receipts don't turn black in JPEG
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Activity actions recorded in a group's feed
+const (
+	ActionGroupCreated      = "group.created"
+	ActionMemberAdded       = "member.added"
+	ActionExpenseCreated    = "expense.created"
+	ActionExpenseUpdated    = "expense.updated"
+	ActionExpenseDeleted    = "expense.deleted"
+	ActionExpenseMerged     = "expense.merged" // a duplicate folded into it
+	ActionSettlementCreated = "settlement.created"
+	ActionCommentCreated    = "comment.created"
+	ActionAttachmentAdded   = "attachment.added"
+	ActionAttachmentDeleted = "attachment.deleted"
+)
+
+type Activity struct {
+	ID         string          `json:"id"`
+	GroupID    string          `json:"group_id"`
+	ActorID    string          `json:"actor_id,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type,omitempty"` // "expense", "settlement", "user", ...
+	TargetID   string          `json:"target_id,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"` // small summary for rendering the feed
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type Comment struct {
+	ID         string    `json:"id"`
+	GroupID    string    `json:"group_id"`
+	TargetType string    `json:"target_type"` // "expense" | "settlement"
+	TargetID   string    `json:"target_id"`
+	AuthorID   string    `json:"author_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// Attachment is a bill photo or PDF stored in blob storage.
+type Attachment struct {
+	ID           string    `json:"id"`
+	ExpenseID    string    `json:"expense_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"` // empty for PDFs / undecodable images
+	HasThumbnail bool      `json:"has_thumbnail"`
+	UploadedBy   string    `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
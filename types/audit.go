@@ -0,0 +1,21 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is one row of the append-only audit log. Before is empty for
+// creates and After for deletes.
+type AuditEntry struct {
+	ID        string          `json:"id"`
+	ActorID   string          `json:"actor_id,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	GroupID   string          `json:"group_id,omitempty"`
+	Entity    string          `json:"entity"` // "expense", "settlement", "user", "group", ...
+	EntityID  string          `json:"entity_id"`
+	Action    string          `json:"action"` // "create" | "update" | "delete"
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,47 @@
+package types
+
+import "time"
+
+type Category string
+
+const (
+	CategoryFood          Category = "food"
+	CategoryGroceries     Category = "groceries"
+	CategoryTravel        Category = "travel"
+	CategoryRent          Category = "rent"
+	CategoryUtilities     Category = "utilities"
+	CategoryEntertainment Category = "entertainment"
+	CategoryShopping      Category = "shopping"
+	CategoryHealth        Category = "health"
+	CategoryOther         Category = "other"
+)
+
+var BuiltinCategories = []Category{
+	CategoryFood, CategoryGroceries, CategoryTravel, CategoryRent, CategoryUtilities,
+	CategoryEntertainment, CategoryShopping, CategoryHealth, CategoryOther,
+}
+
+func (c Category) IsBuiltin() bool {
+	for _, b := range BuiltinCategories {
+		if c == b {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupCategory is a custom category a group adds on top of the built-in
+// ones, e.g. "maid" or "cylinder". Keywords feed the auto-categorizer.
+type GroupCategory struct {
+	GroupID   string    `json:"group_id"`
+	Name      Category  `json:"name"`
+	Keywords  []string  `json:"keywords,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CategoryTotal is one row of the per-category report.
+type CategoryTotal struct {
+	Category Category `json:"category"`
+	Count    int      `json:"count"`
+	Total    Money    `json:"total_paise"`
+}
@@ -8,10 +8,13 @@ type Expense struct {
 	ID          string    `json:"id"`
 	GroupID     string    `json:"group_id"`
 	PaidBy      string    `json:"paid_by"`
-	AmountPaise int64     `json:"amount_paise"`
+	AmountPaise Money     `json:"amount_paise"`
 	Currency    string    `json:"currency"` // "INR"
 	Note        string    `json:"note"`
+	Category    Category  `json:"category"`
+	MerchantVPA string    `json:"merchant_vpa,omitempty"` // when paid to a merchant over UPI
 	SplitKind   SplitKind `json:"split_kind"`
+	Version     int64     `json:"version"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
@@ -22,3 +25,16 @@ type ExpenseSplit struct {
 	UserID    string `json:"user_id"`
 	Exact     Money  `json:"exact"` // paise each user owes for this expense
 }
+
+// ExpenseSplitDetail is a split row with the participant's display name.
+type ExpenseSplitDetail struct {
+	ExpenseSplit
+	UserName string `json:"user_name"`
+}
+
+// ExpenseDetail is an expense with everything needed to show "who owes what".
+type ExpenseDetail struct {
+	Expense
+	PaidByName string               `json:"paid_by_name"`
+	Splits     []ExpenseSplitDetail `json:"splits"`
+}
@@ -6,5 +6,6 @@ type Group struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
 	CreatedBy string    `json:"created_by"`
+	Version   int64     `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 }
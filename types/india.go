@@ -0,0 +1,22 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// IST is India Standard Time. Bank statements, SMS and bare dates from
+// clients carry no zone and are all in it.
+var IST = time.FixedZone("IST", 5*60*60+30*60)
+
+// user@psp as it shows up inside narrations and SMS like
+// "UPI-SWIGGY-swiggy@icici-ICIC0000001-412345678901-PAYMENT".
+// Banks use '-' and '/' as field separators there, so '-' is not accepted
+// in the handle even though NPCI technically allows it.
+var vpaRe = regexp.MustCompile(`[a-zA-Z0-9][a-zA-Z0-9._]{1,255}@[a-zA-Z][a-zA-Z0-9]{1,63}`)
+
+// ExtractVPA returns the first VPA found in free text, lower-cased.
+func ExtractVPA(s string) string {
+	return strings.ToLower(vpaRe.FindString(s))
+}
@@ -0,0 +1,32 @@
+package types
+
+import "time"
+
+// Journal is one balanced ledger entry: its postings always sum to zero.
+// Every user has one account per group; a positive balance means the group
+// owes them, negative means they owe the group.
+type Journal struct {
+	ID         string    `json:"id"`
+	GroupID    string    `json:"group_id"`
+	SourceType string    `json:"source_type"` // "expense" | "settlement"
+	SourceID   string    `json:"source_id"`
+	Memo       string    `json:"memo,omitempty"`
+	Postings   []Posting `json:"postings"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Posting struct {
+	UserID string `json:"user_id"`
+	Amount Money  `json:"amount"` // credit > 0, debit < 0
+}
+
+// LedgerIssue is one inconsistency found by verifying the ledger.
+type LedgerIssue struct {
+	Kind       string `json:"kind"` // "unbalanced_journal", "account_mismatch", "unbalanced_group", "source_mismatch"
+	GroupID    string `json:"group_id,omitempty"`
+	JournalID  string `json:"journal_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	SourceType string `json:"source_type,omitempty"`
+	SourceID   string `json:"source_id,omitempty"`
+	Detail     string `json:"detail"`
+}
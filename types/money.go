@@ -1,4 +1,277 @@
 package types
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
 // Money is stored as paise (₹1.00 => 100).
 type Money int64
+
+var (
+	ErrMoneyOverflow = errors.New("money: overflow")
+	ErrMoneyFormat   = errors.New("money: invalid amount")
+)
+
+// ---------- ARITHMETIC ----------
+
+func (m Money) Add(o Money) (Money, error) {
+	s := m + o
+	if (o > 0 && s < m) || (o < 0 && s > m) {
+		return 0, ErrMoneyOverflow
+	}
+	return s, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if o == math.MinInt64 {
+		return 0, ErrMoneyOverflow
+	}
+	return m.Add(-o)
+}
+
+// Mul multiplies by a plain factor (quantity, number of people, ...).
+func (m Money) Mul(n int64) (Money, error) {
+	if m == 0 || n == 0 {
+		return 0, nil
+	}
+	p := m * Money(n)
+	if p/Money(n) != m || (m == -1 && n == math.MinInt64) || (n == -1 && m == math.MinInt64) {
+		return 0, ErrMoneyOverflow
+	}
+	return p, nil
+}
+
+// Sum adds up all amounts, failing on overflow.
+func Sum(ms ...Money) (Money, error) {
+	var total Money
+	for _, m := range ms {
+		var err error
+		if total, err = total.Add(m); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// ---------- ALLOCATION ----------
+
+// Allocate splits m proportionally to weights so the parts always sum to m
+// exactly. Each part is floor(m*w/sum); the leftover paise go to the parts
+// with the largest remainders, earlier indexes first on ties.
+// Weights must be >= 0 with a positive total.
+func (m Money) Allocate(weights []int64) ([]Money, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("money: no weights")
+	}
+	var total uint64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, errors.New("money: negative weight")
+		}
+		var carry uint64
+		total, carry = bits.Add64(total, uint64(w), 0)
+		if carry != 0 || total > math.MaxInt64 {
+			return nil, ErrMoneyOverflow
+		}
+	}
+	if total == 0 {
+		return nil, errors.New("money: weights sum to zero")
+	}
+
+	neg := m < 0
+	abs := uint64(m)
+	if neg {
+		abs = uint64(-m) // MinInt64 wraps to 1<<63, still correct as uint64
+	}
+
+	out := make([]Money, len(weights))
+	rems := make([]uint64, len(weights))
+	var given uint64
+	for i, w := range weights {
+		// 128-bit intermediate so amount*weight can't overflow
+		hi, lo := bits.Mul64(abs, uint64(w))
+		q, r := bits.Div64(hi, lo, total)
+		out[i], rems[i] = Money(q), r
+		given += q
+	}
+
+	for left := abs - given; left > 0; left-- {
+		best := -1
+		for i, r := range rems {
+			if weights[i] > 0 && (best < 0 || r > rems[best]) {
+				best = i
+			}
+		}
+		out[best]++
+		rems[best] = 0
+	}
+
+	if neg {
+		for i := range out {
+			out[i] = -out[i]
+		}
+	}
+	return out, nil
+}
+
+// Split divides m into n parts differing by at most one paisa; the first
+// parts get the extra paise.
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, errors.New("money: split into zero parts")
+	}
+	w := make([]int64, n)
+	for i := range w {
+		w[i] = 1
+	}
+	return m.Allocate(w)
+}
+
+// ---------- FORMATTING ----------
+
+// String formats with Indian digit grouping: ₹1,23,45,678.90
+func (m Money) String() string {
+	sign := ""
+	abs := uint64(m)
+	if m < 0 {
+		sign = "-"
+		abs = uint64(-m)
+	}
+	return fmt.Sprintf("%s₹%s.%02d", sign, groupIndian(strconv.FormatUint(abs/100, 10)), abs%100)
+}
+
+// Rupees is the plain decimal form used by UPI ("am=1250.50"), no grouping or symbol.
+func (m Money) Rupees() string {
+	sign := ""
+	abs := uint64(m)
+	if m < 0 {
+		sign = "-"
+		abs = uint64(-m)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, abs/100, abs%100)
+}
+
+// groupIndian puts commas after the last three digits and then every two (lakh/crore).
+func groupIndian(d string) string {
+	if len(d) <= 3 {
+		return d
+	}
+	head, tail := d[:len(d)-3], d[len(d)-3:]
+	var parts []string
+	for len(head) > 2 {
+		parts = append([]string{head[len(head)-2:]}, parts...)
+		head = head[:len(head)-2]
+	}
+	parts = append([]string{head}, parts...)
+	return strings.Join(parts, ",") + "," + tail
+}
+
+// ---------- PARSING ----------
+
+var groupedRe = regexp.MustCompile(`^(?:\d{1,3}(?:,\d{3})*|\d{1,2}(?:,\d{2})*,\d{3})$`)
+
+// ParseMoney reads user input in rupees: "1,250.5", "₹99", "Rs. 1,00,000",
+// "INR 45.00", "-12". At most two decimals; no float rounding involved.
+// Commas must group digits, Indian style (1,00,000) or international
+// (100,000).
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg, s = true, strings.TrimSpace(s[1:])
+	}
+	for _, p := range []string{"₹", "INR", "Rs.", "Rs", "rs.", "rs"} {
+		if strings.HasPrefix(s, p) {
+			s = strings.TrimSpace(s[len(p):])
+			break
+		}
+	}
+	if strings.HasPrefix(s, "-") && !neg {
+		neg, s = true, s[1:]
+	}
+	whole, frac, hasDot := strings.Cut(s, ".")
+	if whole == "" && (!hasDot || frac == "") {
+		return 0, ErrMoneyFormat
+	}
+	if strings.Contains(whole, ",") {
+		if !groupedRe.MatchString(whole) {
+			return 0, fmt.Errorf("%w: misplaced comma", ErrMoneyFormat)
+		}
+		whole = strings.ReplaceAll(whole, ",", "")
+	}
+	if len(frac) > 2 {
+		return 0, fmt.Errorf("%w: more than 2 decimal places", ErrMoneyFormat)
+	}
+	for _, part := range []string{whole, frac} {
+		for _, r := range part {
+			if r < '0' || r > '9' {
+				return 0, ErrMoneyFormat
+			}
+		}
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrMoneyOverflow
+	}
+	f, _ := strconv.ParseInt(frac, 10, 64)
+	m, err := Money(w).Mul(100)
+	if err != nil {
+		return 0, err
+	}
+	if m, err = m.Add(Money(f)); err != nil {
+		return 0, err
+	}
+	if neg {
+		m = -m
+	}
+	return m, nil
+}
+
+// ---------- JSON ----------
+
+// Money is encoded as integer paise. On input we also accept a string in
+// rupees ("₹1,250.50") so clients can pass through what the user typed.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		v, err := ParseMoney(s)
+		if err != nil {
+			return err
+		}
+		*m = v
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("%w: expected integer paise", ErrMoneyFormat)
+	}
+	*m = Money(n)
+	return nil
+}
+
+// MoneyDisplay is for responses that want a ready-to-show string next to the paise.
+type MoneyDisplay struct {
+	Paise     Money  `json:"paise"`
+	Formatted string `json:"formatted"`
+}
+
+func (m Money) Display() MoneyDisplay {
+	return MoneyDisplay{Paise: m, Formatted: m.String()}
+}
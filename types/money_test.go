@@ -0,0 +1,151 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr error
+	}{
+		{in: "1,250.5", want: 125050},
+		{in: "₹99", want: 9900},
+		{in: "Rs. 1,00,000", want: 10000000},
+		{in: "INR 45.00", want: 4500},
+		{in: "rs.7", want: 700},
+		{in: "-12", want: -1200},
+		{in: "- ₹5", want: -500},
+		{in: "₹-5", want: -500},
+		{in: ".5", want: 50},
+		{in: "12.", want: 1200},
+		{in: "  0.01 ", want: 1},
+		{in: "1,23,45,678.90", want: 1234567890},
+		{in: "1,000,000", want: 100000000},
+		{in: "12,345", want: 1234500},
+
+		{in: "", wantErr: ErrMoneyFormat},
+		{in: ".", wantErr: ErrMoneyFormat},
+		{in: "₹", wantErr: ErrMoneyFormat},
+		{in: "1,,2", wantErr: ErrMoneyFormat},
+		{in: "12,", wantErr: ErrMoneyFormat},
+		{in: ",12", wantErr: ErrMoneyFormat},
+		{in: "1,2345", wantErr: ErrMoneyFormat},
+		{in: "1,00,000,000", wantErr: ErrMoneyFormat},
+		{in: "1.2,5", wantErr: ErrMoneyFormat},
+		{in: "1.234", wantErr: ErrMoneyFormat},
+		{in: "1e3", wantErr: ErrMoneyFormat},
+		{in: "--5", wantErr: ErrMoneyFormat},
+		{in: "abc", wantErr: ErrMoneyFormat},
+		{in: "99999999999999999999", wantErr: ErrMoneyOverflow},
+		{in: "92233720368547758.08", wantErr: ErrMoneyOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseMoney(%q) = %d, %v; want %v", tt.in, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseMoney(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		in   Money
+		want string
+	}{
+		{0, "₹0.00"},
+		{5, "₹0.05"},
+		{99900, "₹999.00"},
+		{100000, "₹1,000.00"},
+		{10000000, "₹1,00,000.00"},
+		{12345678990, "₹12,34,56,789.90"},
+		{-150, "-₹1.50"},
+		{math.MinInt64, "-₹92,23,37,20,36,85,47,758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Money
+		weights []int64
+		want    []Money
+	}{
+		{name: "even three ways", m: 100, weights: []int64{1, 1, 1}, want: []Money{34, 33, 33}},
+		{name: "negative", m: -100, weights: []int64{1, 1, 1}, want: []Money{-34, -33, -33}},
+		{name: "largest remainder gets the paisa", m: 7, weights: []int64{2, 3, 5}, want: []Money{1, 2, 4}},
+		{name: "ties go to the earlier part", m: 1000, weights: []int64{1, 2, 3}, want: []Money{167, 333, 500}},
+		{name: "zero weight gets nothing", m: 101, weights: []int64{0, 1, 1}, want: []Money{0, 51, 50}},
+		{name: "exact", m: 500, weights: []int64{1, 1, 3}, want: []Money{100, 100, 300}},
+		{name: "no overflow on big amounts", m: math.MaxInt64, weights: []int64{1, 1}, want: []Money{math.MaxInt64/2 + 1, math.MaxInt64 / 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Allocate(tt.weights)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Allocate(%v) = %v, want %v", tt.weights, got, tt.want)
+			}
+		})
+	}
+
+	for _, weights := range [][]int64{nil, {1, -1}, {0, 0}, {math.MaxInt64, 1}} {
+		if _, err := Money(100).Allocate(weights); err == nil {
+			t.Errorf("Allocate(%v): want an error", weights)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `1250`, want: 1250},
+		{in: `-5`, want: -5},
+		{in: `"₹1,250.50"`, want: 125050},
+		{in: `"Rs. 1,00,000"`, want: 10000000},
+		{in: `"1,,2"`, wantErr: true},
+		{in: `12.5`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `true`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got struct {
+				Amount Money `json:"amount"`
+			}
+			err := json.Unmarshal([]byte(`{"amount":`+tt.in+`}`), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %d, want an error", got.Amount)
+				}
+				return
+			}
+			if err != nil || got.Amount != tt.want {
+				t.Fatalf("got %d, %v; want %d", got.Amount, err, tt.want)
+			}
+		})
+	}
+}
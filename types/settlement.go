@@ -4,6 +4,7 @@ import "time"
 
 type Settlement struct {
 	ID        string    `json:"id"`
+	GroupID   string    `json:"group_id"`
 	FromUser  string    `json:"from_user"`
 	ToUser    string    `json:"to_user"`
 	Amount    Money     `json:"amount"`
@@ -13,7 +13,7 @@ type SplitInputUser struct {
 	UserID    string `json:"user_id"`
 	Shares    *int64 `json:"shares,omitempty"`     // for shares
 	PercentBP *int64 `json:"percent_bp,omitempty"` // for percent
-	Exact     *int64 `json:"exact,omitempty"`      // for exact
+	Exact     *Money `json:"exact,omitempty"`      // for exact
 }
 
 type SplitInput struct {
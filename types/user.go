@@ -9,5 +9,6 @@ type User struct {
 	Email     *string   `json:"email,omitempty"`
 	Phone     *string   `json:"phone,omitempty"`
 	UPI       *string   `json:"upi_vpa,omitempty"`
+	Version   int64     `json:"version"` // bumped on every update, sent as the ETag
 	CreatedAt time.Time `json:"created_at"`
 }